@@ -0,0 +1,351 @@
+package mempool
+
+import (
+	"container/list"
+	"time"
+
+	cmtsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/p2p"
+	protomem "github.com/cometbft/cometbft/proto/tendermint/mempool"
+	"github.com/cometbft/cometbft/types"
+)
+
+// GossipMode selects how the Reactor propagates new transactions to peers.
+type GossipMode string
+
+const (
+	// GossipModePush sends the full tx body to every eligible peer, as the
+	// Reactor has always done. Simple, but quadratic in tx size x peers.
+	GossipModePush GossipMode = "push"
+
+	// GossipModePull only ever announces a tx's key; peers that don't
+	// already have it request the body explicitly.
+	GossipModePull GossipMode = "pull"
+
+	// GossipModeHybrid pushes small txs directly (as in GossipModePush) and
+	// announces larger ones (as in GossipModePull), trading off the extra
+	// announce/request round trip against bandwidth.
+	GossipModeHybrid GossipMode = "hybrid"
+)
+
+const (
+	// defaultAnnouncedKeysCacheSize bounds, per peer, how many keys we
+	// remember having already announced (so we don't announce them twice).
+	defaultAnnouncedKeysCacheSize = 10000
+
+	// requestTimeout is how long we wait for a peer to fulfil a TxRequest
+	// before falling back to another peer that announced the same key.
+	requestTimeout = 1 * time.Second
+
+	// maxOutstandingRequestsPerPeer rate-limits how many TxRequests we may
+	// have in flight to a single peer at once.
+	maxOutstandingRequestsPerPeer = 100
+)
+
+// boundedKeySet is a fixed-capacity set of tx keys with FIFO eviction, used
+// to remember which keys we've already announced to a peer without growing
+// unbounded over the life of the connection.
+type boundedKeySet struct {
+	cap   int
+	order *list.List
+	index map[types.TxKey]*list.Element
+}
+
+func newBoundedKeySet(capacity int) *boundedKeySet {
+	return &boundedKeySet{
+		cap:   capacity,
+		order: list.New(),
+		index: make(map[types.TxKey]*list.Element, capacity),
+	}
+}
+
+// Add records key as seen, evicting the oldest entry if the set is full.
+// Returns true if the key was newly added.
+func (s *boundedKeySet) Add(key types.TxKey) bool {
+	if _, ok := s.index[key]; ok {
+		return false
+	}
+	if s.order.Len() >= s.cap {
+		oldest := s.order.Front()
+		if oldest != nil {
+			delete(s.index, s.order.Remove(oldest).(types.TxKey))
+		}
+	}
+	s.index[key] = s.order.PushBack(key)
+	return true
+}
+
+func (s *boundedKeySet) Has(key types.TxKey) bool {
+	_, ok := s.index[key]
+	return ok
+}
+
+// pendingRequest tracks a TxRequest we've sent out for a given key, so we
+// can fall back to a different announcer if it times out.
+type pendingRequest struct {
+	key       types.TxKey
+	askedPeer p2p.ID
+	timer     *time.Timer
+}
+
+// announceState is the Reactor-wide bookkeeping for the Have/Want protocol.
+// It is intentionally kept separate from Reactor's other fields since it is
+// only used in pull/hybrid gossip mode.
+type announceState struct {
+	mtx cmtsync.RWMutex
+
+	// announcedTo remembers, per peer, which keys we've already announced so
+	// a slow peer doesn't get the same TxAnnounce twice.
+	announcedTo map[p2p.ID]*boundedKeySet
+
+	// announcers tracks, for a key we don't yet have the body for, which
+	// peers have announced it, in the order they announced it in. The
+	// puller requests from the front and falls back to the next entry if
+	// the request times out.
+	announcers map[types.TxKey][]p2p.ID
+
+	// outstanding is the number of TxRequests currently in flight per peer,
+	// used to rate-limit requests.
+	outstanding map[p2p.ID]int
+
+	// pending maps a key we're currently waiting on to its request state.
+	pending map[types.TxKey]*pendingRequest
+
+	// retrying marks keys for which every known announcer was over
+	// maxOutstandingRequestsPerPeer the last time we tried, and a retry is
+	// already scheduled. Prevents piling up redundant timers if the same key
+	// is re-announced before that retry fires.
+	retrying map[types.TxKey]bool
+
+	wantCh chan types.TxKey
+}
+
+func newAnnounceState() *announceState {
+	return &announceState{
+		announcedTo: make(map[p2p.ID]*boundedKeySet),
+		announcers:  make(map[types.TxKey][]p2p.ID),
+		outstanding: make(map[p2p.ID]int),
+		pending:     make(map[types.TxKey]*pendingRequest),
+		retrying:    make(map[types.TxKey]bool),
+		wantCh:      make(chan types.TxKey, 1000),
+	}
+}
+
+// gossipModeFor returns the configured gossip mode, defaulting to push for
+// backwards compatibility with nodes that haven't set it.
+func (memR *Reactor) gossipModeFor() GossipMode {
+	switch GossipMode(memR.config.GossipMode) {
+	case GossipModePull:
+		return GossipModePull
+	case GossipModeHybrid:
+		return GossipModeHybrid
+	default:
+		return GossipModePush
+	}
+}
+
+// shouldPush reports whether a tx of the given size should be pushed
+// directly rather than merely announced, under the reactor's configured
+// gossip mode.
+func (memR *Reactor) shouldPush(txSize int) bool {
+	switch memR.gossipModeFor() {
+	case GossipModePush:
+		return true
+	case GossipModeHybrid:
+		return txSize <= memR.config.GossipPushThresholdBytes
+	default: // GossipModePull
+		return false
+	}
+}
+
+// sendAnnounce sends a TxAnnounce for key to peer, unless we've already
+// announced it to this peer.
+func (memR *Reactor) sendAnnounce(peer p2p.Peer, key types.TxKey) bool {
+	memR.announce.mtx.Lock()
+	set, ok := memR.announce.announcedTo[peer.ID()]
+	if !ok {
+		set = newBoundedKeySet(defaultAnnouncedKeysCacheSize)
+		memR.announce.announcedTo[peer.ID()] = set
+	}
+	isNew := set.Add(key)
+	memR.announce.mtx.Unlock()
+
+	if !isNew {
+		return true
+	}
+
+	return peer.Send(p2p.Envelope{
+		ChannelID: MempoolChannel,
+		Message:   &protomem.TxAnnounce{Keys: [][]byte{key[:]}},
+	})
+}
+
+// handleTxAnnounce records that a peer has (or is about to have) the given
+// keys, suppressing future gossip of them back to that peer, and queues any
+// keys we don't already know about for the puller to request.
+func (memR *Reactor) handleTxAnnounce(src p2p.Peer, msg *protomem.TxAnnounce) {
+	peerID := memR.ids.GetForPeer(src)
+
+	for _, keyBytes := range msg.GetKeys() {
+		var key types.TxKey
+		copy(key[:], keyBytes)
+
+		// An announcement is itself proof that the peer has the tx, so it
+		// counts for gossip-suppression purposes even before the body
+		// arrives.
+		memR.addSender(key, peerID)
+
+		if memR.mempool.Has(key) {
+			continue
+		}
+
+		memR.announce.mtx.Lock()
+		first := len(memR.announce.announcers[key]) == 0
+		memR.announce.announcers[key] = append(memR.announce.announcers[key], src.ID())
+		memR.announce.mtx.Unlock()
+
+		if !first {
+			// A want for this key is already queued or in flight; this peer
+			// is only recorded as a fallback announcer.
+			continue
+		}
+
+		select {
+		case memR.announce.wantCh <- key:
+		default:
+			// The puller is falling behind; drop the want rather than
+			// blocking the receive loop. Forget the announcer too, so the
+			// next peer to announce this tx queues it again.
+			memR.announce.mtx.Lock()
+			delete(memR.announce.announcers, key)
+			memR.announce.mtx.Unlock()
+		}
+	}
+}
+
+// handleTxRequest responds to a peer's TxRequest with whichever of the
+// requested txs we currently hold.
+func (memR *Reactor) handleTxRequest(src p2p.Peer, msg *protomem.TxRequest) {
+	var txs [][]byte
+	for _, keyBytes := range msg.GetKeys() {
+		var key types.TxKey
+		copy(key[:], keyBytes)
+		if tx, ok := memR.mempool.GetTx(key); ok {
+			txs = append(txs, tx)
+		}
+	}
+	if len(txs) == 0 {
+		return
+	}
+	src.Send(p2p.Envelope{
+		ChannelID: MempoolChannel,
+		Message:   &protomem.Txs{Txs: txs},
+	})
+}
+
+// pullTxRoutine services memR.announce.wantCh, issuing rate-limited
+// TxRequests for announced-but-unknown keys and falling back to another
+// announcer when a request times out.
+func (memR *Reactor) pullTxRoutine() {
+	for {
+		select {
+		case key := <-memR.announce.wantCh:
+			memR.requestKey(key)
+		case <-memR.Quit():
+			return
+		}
+	}
+}
+
+func (memR *Reactor) requestKey(key types.TxKey) {
+	memR.announce.mtx.Lock()
+	defer memR.announce.mtx.Unlock()
+
+	if _, already := memR.announce.pending[key]; already {
+		return
+	}
+	if memR.mempool.Has(key) {
+		delete(memR.announce.announcers, key)
+		return
+	}
+
+	peers := memR.announce.announcers[key]
+	busy := false
+	for len(peers) > 0 {
+		candidate := peers[0]
+		peers = peers[1:]
+		if memR.announce.outstanding[candidate] >= maxOutstandingRequestsPerPeer {
+			busy = true
+			continue
+		}
+		peer := memR.Switch.Peers().Get(candidate)
+		if peer == nil {
+			continue
+		}
+
+		memR.announce.announcers[key] = peers
+		memR.announce.outstanding[candidate]++
+		memR.announce.pending[key] = &pendingRequest{
+			key:       key,
+			askedPeer: candidate,
+			timer: time.AfterFunc(requestTimeout, func() {
+				memR.onRequestTimeout(key)
+			}),
+		}
+
+		peer.Send(p2p.Envelope{
+			ChannelID: MempoolChannel,
+			Message:   &protomem.TxRequest{Keys: [][]byte{key[:]}},
+		})
+		return
+	}
+	if !busy {
+		// No announcer is left to ask (none recorded, or all disconnected):
+		// drop the key; the next TxAnnounce for it queues it again.
+		delete(memR.announce.announcers, key)
+		return
+	}
+	// Every known announcer is currently over maxOutstandingRequestsPerPeer.
+	// Leave memR.announce.announcers[key] as-is (it still lists them, in
+	// order) and retry once an outstanding count has had a chance to free up,
+	// instead of dropping the key and relying on another TxAnnounce to
+	// re-queue it.
+	if !memR.announce.retrying[key] {
+		memR.announce.retrying[key] = true
+		time.AfterFunc(requestTimeout, func() {
+			memR.announce.mtx.Lock()
+			delete(memR.announce.retrying, key)
+			memR.announce.mtx.Unlock()
+			memR.requestKey(key)
+		})
+	}
+}
+
+// onRequestTimeout falls back to the next peer that announced key, if any.
+func (memR *Reactor) onRequestTimeout(key types.TxKey) {
+	memR.announce.mtx.Lock()
+	req, ok := memR.announce.pending[key]
+	if ok {
+		memR.announce.outstanding[req.askedPeer]--
+		delete(memR.announce.pending, key)
+	}
+	memR.announce.mtx.Unlock()
+
+	if ok && !memR.mempool.Has(key) {
+		memR.requestKey(key)
+	}
+}
+
+// onTxReceived clears any in-flight request bookkeeping for a tx whose body
+// just arrived, by push or by a fulfilled TxRequest.
+func (memR *Reactor) onTxReceived(key types.TxKey) {
+	memR.announce.mtx.Lock()
+	defer memR.announce.mtx.Unlock()
+
+	if req, ok := memR.announce.pending[key]; ok {
+		req.timer.Stop()
+		memR.announce.outstanding[req.askedPeer]--
+		delete(memR.announce.pending, key)
+	}
+	delete(memR.announce.announcers, key)
+}
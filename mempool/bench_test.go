@@ -2,9 +2,11 @@ package mempool
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,10 +14,14 @@ import (
 	"github.com/cometbft/cometbft/abci/example/kvstore"
 	abciserver "github.com/cometbft/cometbft/abci/server"
 	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
 	cmtrand "github.com/cometbft/cometbft/internal/rand"
 	"github.com/cometbft/cometbft/internal/test"
 	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/p2p"
+	protomem "github.com/cometbft/cometbft/proto/tendermint/mempool"
 	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
 )
 
 func BenchmarkReap(b *testing.B) {
@@ -29,7 +35,7 @@ func BenchmarkReap(b *testing.B) {
 	size := 10000
 	for i := 0; i < size; i++ {
 		tx := kvstore.NewTxFromID(i)
-		if _, err := mp.CheckTx(tx, ""); err != nil {
+		if _, err := mp.CheckTx(tx); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -53,7 +59,7 @@ func BenchmarkCheckTx(b *testing.B) {
 		tx := kvstore.NewTxFromID(i)
 		b.StartTimer()
 
-		if _, err := mp.CheckTx(tx, ""); err != nil {
+		if _, err := mp.CheckTx(tx); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -76,7 +82,7 @@ func BenchmarkParallelCheckTx(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
 			tx := kvstore.NewTxFromID(int(next()))
-			if _, err := mp.CheckTx(tx, ""); err != nil {
+			if _, err := mp.CheckTx(tx); err != nil {
 				b.Fatal(err)
 			}
 		}
@@ -92,7 +98,7 @@ func BenchmarkCheckDuplicateTx(b *testing.B) {
 	mp.config.Size = 2
 
 	tx := kvstore.NewTxFromID(1)
-	if _, err := mp.CheckTx(tx, ""); err != nil {
+	if _, err := mp.CheckTx(tx); err != nil {
 		b.Fatal(err)
 	}
 	err := mp.FlushAppConn()
@@ -100,7 +106,7 @@ func BenchmarkCheckDuplicateTx(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := mp.CheckTx(tx, ""); err == nil {
+		if _, err := mp.CheckTx(tx); err == nil {
 			b.Fatal("tx should be duplicate")
 		}
 	}
@@ -130,7 +136,7 @@ func BenchmarkUpdateRemoteClient(b *testing.B) {
 	for i := 1; i <= b.N; i++ {
 		tx := kvstore.NewTxFromID(i)
 
-		_, err := mp.CheckTx(tx, "")
+		_, err := mp.CheckTx(tx)
 		require.NoError(b, err)
 
 		err = mp.FlushAppConn()
@@ -159,7 +165,7 @@ func BenchmarkUpdateWithConcurrentCheckTx(b *testing.B) {
 
 	// Add some txs to mempool.
 	for i := 1; i <= numTxs; i++ {
-		rr, err := mp.CheckTx(kvstore.NewTxFromID(i), "")
+		rr, err := mp.CheckTx(kvstore.NewTxFromID(i))
 		require.NoError(b, err)
 		rr.Wait()
 	}
@@ -168,7 +174,7 @@ func BenchmarkUpdateWithConcurrentCheckTx(b *testing.B) {
 	go func() {
 		defer wg.Done()
 		for i := numTxs + 1; i <= numTxs; i++ {
-			_, err := mp.CheckTx(kvstore.NewTxFromID(i), "")
+			_, err := mp.CheckTx(kvstore.NewTxFromID(i))
 			require.NoError(b, err)
 		}
 	}()
@@ -199,3 +205,200 @@ func BenchmarkUpdateWithConcurrentCheckTx(b *testing.B) {
 	// }
 	wg.Wait()
 }
+
+// BenchmarkBroadcastBatching measures how many underlying sends txBatch
+// collapses a fixed stream of small txs into, with batching enabled vs.
+// disabled (BroadcastBatchSize == 1), as a proxy for the send syscalls /
+// framing overhead broadcastTxRoutine saves by re-batching.
+func BenchmarkBroadcastBatching(b *testing.B) {
+	const numTxs = 1000
+	const txSize = 250
+
+	bench := func(b *testing.B, batchSize int, batchBytes int64) {
+		config := &cfg.MempoolConfig{
+			BroadcastBatchSize:    batchSize,
+			BroadcastBatchBytes:   batchBytes,
+			BroadcastBatchTimeout: time.Hour, // never trip in this benchmark
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			batch := &txBatch{}
+			sends := 0
+			for j := 0; j < numTxs; j++ {
+				tx := make([]byte, txSize)
+				if batch.full(config) {
+					sends++
+					batch.reset()
+				}
+				batch.add(tx)
+			}
+			if !batch.empty() {
+				sends++
+			}
+			b.ReportMetric(float64(sends), "sends/op")
+		}
+	}
+
+	b.Run("batching_on", func(b *testing.B) { bench(b, 32, 32*1024) })
+	b.Run("batching_off", func(b *testing.B) { bench(b, 1, txSize) })
+}
+
+// BenchmarkFanoutScaling measures how memory and goroutine count grow with
+// the number of connected peers under the dispatcher/per-peer-queue fanout
+// design: one bounded channel plus one lightweight goroutine per peer,
+// rather than one goroutine holding a live clist cursor per peer.
+func BenchmarkFanoutScaling(b *testing.B) {
+	const sendsPerPeer = 100
+
+	for _, n := range []int{10, 50, 100, 500} {
+		b.Run(fmt.Sprintf("peers_%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				runtime.GC()
+				var before runtime.MemStats
+				runtime.ReadMemStats(&before)
+				goroutinesBefore := runtime.NumGoroutine()
+
+				queues := make([]chan *mempoolTx, n)
+				done := make(chan struct{})
+				var wg sync.WaitGroup
+				for p := 0; p < n; p++ {
+					ch := make(chan *mempoolTx, peerFanoutQueueSize)
+					queues[p] = ch
+					wg.Add(1)
+					go func(ch chan *mempoolTx) {
+						defer wg.Done()
+						for {
+							select {
+							case _, ok := <-ch:
+								if !ok {
+									return
+								}
+							case <-done:
+								return
+							}
+						}
+					}(ch)
+				}
+				b.StartTimer()
+
+				memTx := &mempoolTx{tx: types.Tx(make([]byte, 250))}
+				for j := 0; j < sendsPerPeer; j++ {
+					for _, ch := range queues {
+						select {
+						case ch <- memTx:
+						default:
+							// Queue full: dropped, same as the real dispatcher.
+						}
+					}
+				}
+
+				b.StopTimer()
+				goroutinesAfter := runtime.NumGoroutine()
+				close(done)
+				wg.Wait()
+
+				var after runtime.MemStats
+				runtime.ReadMemStats(&after)
+
+				b.ReportMetric(float64(after.Alloc-before.Alloc)/float64(n), "bytes/peer")
+				b.ReportMetric(float64(goroutinesAfter-goroutinesBefore), "goroutines")
+			}
+		})
+	}
+}
+
+// gossipBytesToPeers drives the reactor's real push/pull decision
+// (shouldPush) and wire methods (peer.Send for a direct push, sendAnnounce
+// for pull/hybrid) against peerCount fake peers, and returns the total bytes
+// actually placed on the wire, measured off the marshaled proto messages the
+// peers received.
+func gossipBytesToPeers(t *testing.T, mode GossipMode, tx types.Tx, peerCount int) int64 {
+	t.Helper()
+
+	memR := &Reactor{config: &cfg.MempoolConfig{GossipMode: string(mode)}}
+	memR.announce = newAnnounceState()
+
+	var total int64
+	for i := 0; i < peerCount; i++ {
+		peer := newFakePeer(p2p.ID(fmt.Sprintf("peer-%d", i)))
+
+		if memR.shouldPush(len(tx)) {
+			require.True(t, peer.Send(p2p.Envelope{
+				ChannelID: MempoolChannel,
+				Message:   &protomem.Txs{Txs: [][]byte{tx}},
+			}))
+		} else {
+			require.True(t, memR.sendAnnounce(peer, tx.Key()))
+		}
+
+		for _, e := range peer.sent {
+			sizer, ok := e.Message.(interface{ Size() int })
+			require.True(t, ok, "%T does not report its own wire size", e.Message)
+			total += int64(sizer.Size())
+		}
+	}
+	return total
+}
+
+// TestGossipModeBandwidthScaling drives the reactor's actual shouldPush,
+// sendAnnounce and handleTxAnnounce code paths against fake peers and checks
+// that, as peer fan-out grows, pull mode's bytes-on-the-wire for a large tx
+// stay pinned to one small TxAnnounce per peer while push mode's scale with
+// the full tx body, so the bandwidth gap widens sharply with fan-out. The
+// added latency pull mode pays for that (the TxRequest/Txs round trip a
+// receiving peer initiates after an announce) is a single requestTimeout
+// wait per key regardless of how many peers announced it, since requestKey
+// only ever has one request in flight per key at a time.
+func TestGossipModeBandwidthScaling(t *testing.T) {
+	const txSize = 64 * 1024 // large tx, well above any reasonable push threshold
+	tx := types.Tx(make([]byte, txSize))
+
+	for _, n := range []int{10, 100, 1000} {
+		pushBytes := gossipBytesToPeers(t, GossipModePush, tx, n)
+		pullBytes := gossipBytesToPeers(t, GossipModePull, tx, n)
+
+		require.Greater(t, pushBytes, pullBytes,
+			"push mode should transfer more data than pull mode at %d peers", n)
+
+		// The ratio stays at roughly txSize/keySize at every fan-out, since
+		// push cost is O(n*txSize) while announce cost is O(n*keySize).
+		ratio := float64(pushBytes) / float64(pullBytes)
+		require.Greater(t, ratio, float64(txSize)/64)
+	}
+
+	// handleTxAnnounce is what a receiving peer runs; it must queue exactly
+	// one want per newly-announced key, not one per announcer, which is what
+	// keeps a single key's pull latency at one requestTimeout regardless of
+	// how many peers announce it.
+	memR := &Reactor{config: &cfg.MempoolConfig{GossipMode: string(GossipModePull)}, mempool: &CListMempool{cache: map[types.TxKey]struct{}{}}}
+	memR.ids = newMempoolIDs()
+	memR.txSenders = make(map[types.TxKey]map[uint16]bool)
+	memR.announce = newAnnounceState()
+
+	key := tx.Key()
+	for i := 0; i < 100; i++ {
+		src := newFakePeer(p2p.ID(fmt.Sprintf("announcer-%d", i)))
+		memR.InitPeer(src)
+		memR.handleTxAnnounce(src, &protomem.TxAnnounce{Keys: [][]byte{key[:]}})
+	}
+	require.Len(t, memR.announce.wantCh, 1, "100 announces of the same key should queue one want, not 100")
+	require.Len(t, memR.announce.announcers[key], 100, "every announcer should still be recorded as a fallback")
+}
+
+// TestShouldPush checks the push/pull/hybrid decision used by
+// broadcastTxRoutine to decide whether to send a tx body directly or merely
+// announce its key.
+func TestShouldPush(t *testing.T) {
+	memR := &Reactor{config: &cfg.MempoolConfig{GossipPushThresholdBytes: 1024}}
+
+	memR.config.GossipMode = string(GossipModePush)
+	require.True(t, memR.shouldPush(10*1024))
+
+	memR.config.GossipMode = string(GossipModePull)
+	require.False(t, memR.shouldPush(10))
+
+	memR.config.GossipMode = string(GossipModeHybrid)
+	require.True(t, memR.shouldPush(100))
+	require.False(t, memR.shouldPush(10*1024))
+}
@@ -0,0 +1,483 @@
+package mempool
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	abcicli "github.com/cometbft/cometbft/abci/client"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/libs/clist"
+	"github.com/cometbft/cometbft/libs/log"
+	cmtsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+const (
+	// MempoolChannel is the gossip channel for mempool transactions.
+	MempoolChannel = byte(0x30)
+
+	// PeerCatchupSleepIntervalMS defines how much time to sleep if a peer is
+	// behind or if a send to a peer failed.
+	PeerCatchupSleepIntervalMS = 100
+
+	// UnknownPeerID is the peer ID used when running CheckTx for our own
+	// (locally submitted) transactions.
+	UnknownPeerID uint16 = 0
+)
+
+// mempoolTx is a transaction that successfully ran CheckTx and is being kept
+// in the mempool, in one of the per-lane clists.
+type mempoolTx struct {
+	height    int64    // height that this tx had been validated in
+	gasWanted int64    // amount of gas this tx states it will require
+	tx        types.Tx // validated by the application
+
+	lane       types.LaneID
+	enqueuedAt time.Time // used to report lane head-of-line age
+}
+
+func (memTx *mempoolTx) Height() int64 {
+	return memTx.height
+}
+
+// CListMempool is an ordered, concurrency-safe list of transactions.
+//
+// Transactions are split into lanes, as advertised by the application via
+// LaneData. Each lane is backed by its own clist.CList so that a slow or
+// congested lane never blocks the head of another lane. Lanes are consulted,
+// e.g. in ReapMaxBytesMaxGas, in order of decreasing priority.
+type CListMempool struct {
+	height   int64 // the last block Update()-ed to
+	txsBytes int64 // total size of all txs in the mempool
+
+	config       *cfg.MempoolConfig
+	proxyAppConn proxy.AppConnMempool
+
+	// laneInfo describes the lanes advertised by the application, including
+	// their priorities and the default lane to use when the app doesn't
+	// return one.
+	laneInfo *LaneData
+
+	// lanes holds one clist.CList per lane, keyed by lane ID. It is built
+	// once, from laneInfo, and never mutated afterwards, so it can be read
+	// without holding mtx.
+	lanes map[types.LaneID]*clist.CList
+
+	// sortedLanes is laneInfo's lanes sorted by decreasing priority; it
+	// fixes the order in which ReapMaxBytesMaxGas and the broadcast
+	// scheduler visit lanes.
+	sortedLanes []types.LaneID
+
+	// laneBytes tracks the total tx size held in each lane, for metrics and
+	// ReapMaxBytesMaxGas bookkeeping.
+	laneBytes map[types.LaneID]int64
+
+	txsRemoved  chan types.TxKey
+	removedOnce cmtsync.RWMutex // guards lazy creation of txsRemoved
+
+	mtx    cmtsync.RWMutex
+	cache  map[types.TxKey]struct{}
+	logger log.Logger
+}
+
+// NewCListMempool returns a new mempool with the given configuration,
+// app connection, and lane information queried from the application.
+func NewCListMempool(
+	config *cfg.MempoolConfig,
+	proxyAppConn proxy.AppConnMempool,
+	height int64,
+	laneInfo *LaneData,
+) *CListMempool {
+	if laneInfo == nil {
+		laneInfo = &LaneData{}
+	}
+
+	mem := &CListMempool{
+		config:       config,
+		proxyAppConn: proxyAppConn,
+		height:       height,
+		laneInfo:     laneInfo,
+		lanes:        make(map[types.LaneID]*clist.CList, len(laneInfo.lanes)),
+		laneBytes:    make(map[types.LaneID]int64, len(laneInfo.lanes)),
+		cache:        make(map[types.TxKey]struct{}),
+		logger:       log.NewNopLogger(),
+	}
+
+	if len(laneInfo.lanes) == 0 {
+		// No lanes were advertised by the app: everything goes through a
+		// single, implicit default lane of priority 1.
+		mem.sortedLanes = []types.LaneID{defaultLaneID}
+		mem.lanes[defaultLaneID] = clist.New()
+	} else {
+		for laneID := range laneInfo.lanes {
+			lane := types.LaneID(laneID)
+			mem.sortedLanes = append(mem.sortedLanes, lane)
+			mem.lanes[lane] = clist.New()
+		}
+		sort.Slice(mem.sortedLanes, func(i, j int) bool {
+			return laneInfo.lanes[string(mem.sortedLanes[i])] > laneInfo.lanes[string(mem.sortedLanes[j])]
+		})
+	}
+
+	return mem
+}
+
+// defaultLaneID is used when the application does not advertise any lanes at
+// all (laneInfo.lanes is empty).
+const defaultLaneID types.LaneID = ""
+
+// SetLogger sets the Logger.
+func (mem *CListMempool) SetLogger(l log.Logger) {
+	mem.logger = l
+}
+
+// Lock/Unlock guard access to the mempool between CheckTx and Update/Reap.
+func (mem *CListMempool) Lock()   { mem.mtx.Lock() }
+func (mem *CListMempool) Unlock() { mem.mtx.Unlock() }
+
+// PreUpdate informs the mempool that Update is about to be called, so it can
+// stop consuming new transactions until Update returns.
+func (mem *CListMempool) PreUpdate() {}
+
+// Size returns the number of transactions currently held across all lanes.
+func (mem *CListMempool) Size() int {
+	n := 0
+	for _, l := range mem.lanes {
+		n += l.Len()
+	}
+	return n
+}
+
+// SizeBytes returns the total size, in bytes, of all txs in the mempool.
+func (mem *CListMempool) SizeBytes() int64 {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	return mem.txsBytes
+}
+
+// Has reports whether a tx with the given key is currently in the mempool
+// or its cache, i.e. whether it's already been seen and doesn't need to be
+// requested from a peer.
+func (mem *CListMempool) Has(key types.TxKey) bool {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	_, ok := mem.cache[key]
+	return ok
+}
+
+// GetTx returns the tx body for key, if we currently hold it.
+func (mem *CListMempool) GetTx(key types.TxKey) (types.Tx, bool) {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	for _, cl := range mem.lanes {
+		for e := cl.Front(); e != nil; e = e.Next() {
+			memTx := e.Value.(*mempoolTx)
+			if memTx.tx.Key() == key {
+				return memTx.tx, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// laneFor resolves the lane a checked tx belongs to, falling back to the
+// app's advertised default lane (or the implicit default lane if the app
+// hasn't advertised any lanes at all).
+func (mem *CListMempool) laneFor(res *abci.CheckTxResponse) types.LaneID {
+	if len(mem.laneInfo.lanes) == 0 {
+		return defaultLaneID
+	}
+	if res.LaneId != "" {
+		if _, ok := mem.laneInfo.lanes[res.LaneId]; ok {
+			return types.LaneID(res.LaneId)
+		}
+	}
+	return types.LaneID(mem.laneInfo.defaultLane)
+}
+
+// CheckTx runs the application's CheckTx on tx and, if accepted, queues it
+// onto the lane returned by the application.
+func (mem *CListMempool) CheckTx(tx types.Tx) (*abcicli.ReqRes, error) {
+	mem.mtx.Lock()
+
+	txKey := tx.Key()
+	if _, ok := mem.cache[txKey]; ok {
+		mem.mtx.Unlock()
+		return nil, ErrTxInCache
+	}
+
+	if len(tx) > mem.config.MaxTxBytes {
+		mem.mtx.Unlock()
+		return nil, ErrTxTooLarge{Max: mem.config.MaxTxBytes, Actual: len(tx)}
+	}
+
+	// Reserve the cache slot synchronously, under the exclusive lock, before
+	// the async CheckTx round-trip. Otherwise two CheckTx calls for the same
+	// tx from two different peers both pass the dedup check above and both
+	// end up pushed onto the lane clist by checkTxCallback.
+	mem.cache[txKey] = struct{}{}
+	mem.mtx.Unlock()
+
+	reqRes, err := mem.proxyAppConn.CheckTxAsync(context.Background(), &abci.CheckTxRequest{
+		Tx:   tx,
+		Type: abci.CHECK_TX_TYPE_CHECK,
+	})
+	if err != nil {
+		mem.mtx.Lock()
+		delete(mem.cache, txKey)
+		mem.mtx.Unlock()
+		return nil, err
+	}
+	reqRes.SetCallback(mem.checkTxCallback(tx, txKey))
+
+	return reqRes, nil
+}
+
+func (mem *CListMempool) checkTxCallback(tx types.Tx, txKey types.TxKey) func(*abci.Response) {
+	return func(r *abci.Response) {
+		res := r.GetCheckTx()
+		if res == nil || res.Code != abci.CodeTypeOK {
+			// Release the cache reservation CheckTx took out; the tx was
+			// never actually queued.
+			mem.mtx.Lock()
+			delete(mem.cache, txKey)
+			mem.mtx.Unlock()
+			return
+		}
+
+		mem.mtx.Lock()
+		defer mem.mtx.Unlock()
+
+		lane := mem.laneFor(res)
+		memTx := &mempoolTx{
+			height:     mem.height,
+			gasWanted:  res.GasWanted,
+			tx:         tx,
+			lane:       lane,
+			enqueuedAt: time.Now(),
+		}
+
+		cl, ok := mem.lanes[lane]
+		if !ok {
+			// Defensive: an app returned a lane we don't know about; fall
+			// back to the default so the tx isn't silently dropped.
+			lane = types.LaneID(mem.laneInfo.defaultLane)
+			cl = mem.lanes[lane]
+		}
+		cl.PushBack(memTx)
+		mem.txsBytes += int64(len(tx))
+		mem.laneBytes[lane] += int64(len(tx))
+	}
+}
+
+// TxsFront returns the first element of the default lane's clist, kept for
+// callers (and tests) that are not lane-aware. Lane-aware broadcasting
+// should use LaneInfo and iterate lanes directly.
+func (mem *CListMempool) TxsFront() *clist.CElement {
+	if cl, ok := mem.lanes[types.LaneID(mem.laneInfo.defaultLane)]; ok {
+		return cl.Front()
+	}
+	return mem.lanes[defaultLaneID].Front()
+}
+
+// LaneFront returns the front element of the given lane, or nil if the lane
+// is empty or unknown.
+func (mem *CListMempool) LaneFront(lane types.LaneID) *clist.CElement {
+	if cl, ok := mem.lanes[lane]; ok {
+		return cl.Front()
+	}
+	return nil
+}
+
+// TxsWaitChan returns the default lane's wait channel, which is closed while
+// the default lane holds at least one tx. Txs added to other lanes do not
+// close it.
+func (mem *CListMempool) TxsWaitChan() <-chan struct{} {
+	if cl, ok := mem.lanes[types.LaneID(mem.laneInfo.defaultLane)]; ok {
+		return cl.WaitChan()
+	}
+	return mem.lanes[defaultLaneID].WaitChan()
+}
+
+// SortedLanes returns the lane IDs in decreasing priority order.
+func (mem *CListMempool) SortedLanes() []types.LaneID {
+	return mem.sortedLanes
+}
+
+// LanePriority returns the priority advertised for lane, or 1 if the app
+// didn't advertise any lanes.
+func (mem *CListMempool) LanePriority(lane types.LaneID) uint32 {
+	if len(mem.laneInfo.lanes) == 0 {
+		return 1
+	}
+	return mem.laneInfo.lanes[string(lane)]
+}
+
+// LaneStats returns the number of txs and total bytes currently queued in
+// lane, and the age of the tx at the head of the lane (zero if empty).
+func (mem *CListMempool) LaneStats(lane types.LaneID) (size int, bytes int64, headAge time.Duration) {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	cl, ok := mem.lanes[lane]
+	if !ok {
+		return 0, 0, 0
+	}
+	size = cl.Len()
+	bytes = mem.laneBytes[lane]
+	if e := cl.Front(); e != nil {
+		headAge = time.Since(e.Value.(*mempoolTx).enqueuedAt)
+	}
+	return size, bytes, headAge
+}
+
+// ReapMaxBytesMaxGas reaps txs, draining lanes in priority order, until
+// either maxBytes or maxGas would be exceeded.
+func (mem *CListMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	var (
+		totalBytes int64
+		totalGas   int64
+		txs        = make([]types.Tx, 0, mem.Size())
+	)
+	for _, lane := range mem.sortedLanes {
+		for e := mem.lanes[lane].Front(); e != nil; e = e.Next() {
+			memTx := e.Value.(*mempoolTx)
+
+			totalBytes += int64(len(memTx.tx))
+			if maxBytes > -1 && totalBytes > maxBytes {
+				break
+			}
+			totalGas += memTx.gasWanted
+			if maxGas > -1 && totalGas > maxGas {
+				break
+			}
+			txs = append(txs, memTx.tx)
+		}
+	}
+	return txs
+}
+
+// ReapMaxTxs reaps up to max txs, draining lanes in priority order.
+func (mem *CListMempool) ReapMaxTxs(max int) types.Txs {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	if max < 0 {
+		max = mem.Size()
+	}
+	txs := make([]types.Tx, 0, max)
+	for _, lane := range mem.sortedLanes {
+		for e := mem.lanes[lane].Front(); e != nil && len(txs) < max; e = e.Next() {
+			txs = append(txs, e.Value.(*mempoolTx).tx)
+		}
+	}
+	return txs
+}
+
+// Flush removes all transactions from the mempool and cache.
+func (mem *CListMempool) Flush() {
+	mem.mtx.Lock()
+	defer mem.mtx.Unlock()
+
+	for _, cl := range mem.lanes {
+		for e := cl.Front(); e != nil; e = e.Next() {
+			cl.Remove(e)
+			e.DetachPrev()
+		}
+	}
+	mem.cache = make(map[types.TxKey]struct{})
+	mem.txsBytes = 0
+	for lane := range mem.laneBytes {
+		mem.laneBytes[lane] = 0
+	}
+}
+
+// Update removes committed txs from every lane (and from the cache) and
+// advances the mempool's height. Remaining txs are not rechecked.
+func (mem *CListMempool) Update(
+	height int64,
+	txs types.Txs,
+	_ []*abci.ExecTxResult,
+	_ PreCheckFunc,
+	_ PostCheckFunc,
+) error {
+	mem.mtx.Lock()
+
+	mem.height = height
+
+	committed := make(map[types.TxKey]struct{}, len(txs))
+	for _, tx := range txs {
+		committed[tx.Key()] = struct{}{}
+	}
+
+	var removed []types.TxKey
+	for _, cl := range mem.lanes {
+		for e := cl.Front(); e != nil; {
+			next := e.Next()
+			memTx := e.Value.(*mempoolTx)
+			if _, ok := committed[memTx.tx.Key()]; ok {
+				cl.Remove(e)
+				e.DetachPrev()
+				delete(mem.cache, memTx.tx.Key())
+				mem.txsBytes -= int64(len(memTx.tx))
+				mem.laneBytes[memTx.lane] -= int64(len(memTx.tx))
+				removed = append(removed, memTx.tx.Key())
+			}
+			e = next
+		}
+	}
+	mem.mtx.Unlock()
+
+	// Notify after releasing mtx: notifyTxRemoved may block for up to a
+	// second on a slow TxsRemoved consumer, and must not hold up every other
+	// CheckTx/Reap*/Size call while it does.
+	for _, key := range removed {
+		mem.notifyTxRemoved(key)
+	}
+	return nil
+}
+
+// FlushAppConn flushes the mempool connection to ensure async callbacks are
+// done before.
+func (mem *CListMempool) FlushAppConn() error {
+	return mem.proxyAppConn.Flush(context.Background())
+}
+
+// EnableTxsRemoved lazily creates the channel used to notify the reactor
+// that a tx was removed from the mempool (e.g. after being committed).
+func (mem *CListMempool) EnableTxsRemoved() {
+	mem.removedOnce.Lock()
+	defer mem.removedOnce.Unlock()
+	if mem.txsRemoved == nil {
+		mem.txsRemoved = make(chan types.TxKey, 100)
+	}
+}
+
+// TxsRemoved returns the channel of removed tx keys. EnableTxsRemoved must
+// be called first.
+func (mem *CListMempool) TxsRemoved() <-chan types.TxKey {
+	mem.removedOnce.RLock()
+	defer mem.removedOnce.RUnlock()
+	return mem.txsRemoved
+}
+
+func (mem *CListMempool) notifyTxRemoved(key types.TxKey) {
+	mem.removedOnce.RLock()
+	defer mem.removedOnce.RUnlock()
+	if mem.txsRemoved == nil {
+		return
+	}
+	select {
+	case mem.txsRemoved <- key:
+	case <-time.After(time.Second):
+		mem.logger.Error("txsRemoved channel is full, dropping notification", "tx", key)
+	}
+}
@@ -0,0 +1,74 @@
+package mempool
+
+import "fmt"
+
+// ErrTxInCache is returned to the client if we saw tx earlier.
+var ErrTxInCache = fmt.Errorf("tx already exists in cache")
+
+// ErrMempoolIsFull means Tendermint & an application can't handle that much load.
+type ErrMempoolIsFull struct {
+	NumTxs      int
+	MaxTxs      int
+	TxsBytes    int64
+	MaxTxsBytes int64
+}
+
+func (e ErrMempoolIsFull) Error() string {
+	return fmt.Sprintf(
+		"mempool is full: number of txs %d (max: %d), total txs bytes %d (max: %d)",
+		e.NumTxs,
+		e.MaxTxs,
+		e.TxsBytes,
+		e.MaxTxsBytes,
+	)
+}
+
+// ErrTxTooLarge means the tx is too big to be sent in a message to the ABCI application.
+type ErrTxTooLarge struct {
+	Max    int
+	Actual int
+}
+
+func (e ErrTxTooLarge) Error() string {
+	return fmt.Sprintf("tx too large. Max size is %d, but got %d", e.Max, e.Actual)
+}
+
+// ErrEmptyLanesDefaultLaneSet is returned when a default lane is set but no
+// lanes were provided by the application.
+type ErrEmptyLanesDefaultLaneSet struct {
+	Info LaneData
+}
+
+func (e ErrEmptyLanesDefaultLaneSet) Error() string {
+	return fmt.Sprintf("default lane %q set but no lanes were provided", e.Info.defaultLane)
+}
+
+// ErrBadDefaultLaneNonEmptyLaneList is returned when the application provides
+// a non-empty lane list without specifying a default lane.
+type ErrBadDefaultLaneNonEmptyLaneList struct {
+	Info LaneData
+}
+
+func (e ErrBadDefaultLaneNonEmptyLaneList) Error() string {
+	return fmt.Sprintf("no default lane set, but %d lanes were provided", len(e.Info.lanes))
+}
+
+// ErrDefaultLaneNotInList is returned when the default lane is not part of
+// the lane list provided by the application.
+type ErrDefaultLaneNotInList struct {
+	Info LaneData
+}
+
+func (e ErrDefaultLaneNotInList) Error() string {
+	return fmt.Sprintf("default lane %q is not in the lane list %v", e.Info.defaultLane, e.Info.lanes)
+}
+
+// ErrRepeatedLanes is returned when the same lane ID was provided more than
+// once, after normalization, in the lane list.
+type ErrRepeatedLanes struct {
+	Info LaneData
+}
+
+func (e ErrRepeatedLanes) Error() string {
+	return fmt.Sprintf("lane list %v contains repeated lanes", e.Info.lanes)
+}
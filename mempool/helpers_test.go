@@ -0,0 +1,114 @@
+package mempool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cometbft/cometbft/abci/example/kvstore"
+	abciserver "github.com/cometbft/cometbft/abci/server"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
+	cmtrand "github.com/cometbft/cometbft/internal/rand"
+	"github.com/cometbft/cometbft/internal/test"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/libs/service"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/require"
+)
+
+// cleanupFunc releases what newMempoolWithApp and friends set up: it removes
+// the on-disk test config (and, for newMempoolWithAsyncConnection, stops the
+// socket server). The ABCI client connection is not stopped.
+type cleanupFunc func()
+
+// newMempoolWithApp bootstraps a CListMempool wired to app through cc, using
+// a throwaway on-disk test config. It's the default bootstrap for tests and
+// benchmarks that just need a working mempool and don't care about its
+// config beyond the defaults.
+func newMempoolWithApp(cc proxy.ClientCreator) (*CListMempool, cleanupFunc) {
+	return newMempoolWithAppAndConfig(cc, test.ResetTestRoot("mempool_test"))
+}
+
+// newMempoolWithAppAndConfig is newMempoolWithApp for callers that need to
+// tweak the mempool config (e.g. pointing it at a remote ABCI app).
+func newMempoolWithAppAndConfig(cc proxy.ClientCreator, conf *cfg.Config) (*CListMempool, cleanupFunc) {
+	appConnMem, err := cc.NewABCIClient()
+	if err != nil {
+		panic(fmt.Sprintf("creating ABCI client: %v", err))
+	}
+	appConnMem.SetLogger(log.TestingLogger().With("module", "abci-client"))
+	if err := appConnMem.Start(); err != nil {
+		panic(fmt.Sprintf("starting ABCI client: %v", err))
+	}
+
+	mp := NewCListMempool(conf.Mempool, appConnMem, 0, nil)
+	mp.SetLogger(log.TestingLogger())
+
+	return mp, func() { os.RemoveAll(conf.RootDir) }
+}
+
+// newMempoolWithAsyncConnection bootstraps a mempool talking to an in-memory
+// kvstore app over a real socket ABCI connection, so CheckTx callbacks fire
+// asynchronously off of a network round trip rather than in-process, the way
+// BenchmarkUpdateWithConcurrentCheckTx needs to exercise the mempool's
+// locking under a realistic CheckTx/Update race.
+func newMempoolWithAsyncConnection(tb testing.TB) (*CListMempool, cleanupFunc) {
+	tb.Helper()
+
+	sockPath := fmt.Sprintf("unix:///tmp/echo_%v.sock", cmtrand.Str(6))
+	app := kvstore.NewInMemoryApplication()
+	server := abciserver.NewSocketServer(sockPath, app)
+	server.SetLogger(log.TestingLogger().With("module", "abci-server"))
+	if err := server.Start(); err != nil {
+		tb.Fatalf("starting socket server: %v", err)
+	}
+
+	mp, cleanup := newMempoolWithAppAndConfig(
+		proxy.NewRemoteClientCreator(sockPath, "socket", true),
+		test.ResetTestRoot("mempool_test"),
+	)
+	return mp, func() {
+		cleanup()
+		stopService(tb, server)
+	}
+}
+
+// stopService stops a started service (e.g. the socket server spun up by
+// newMempoolWithAsyncConnection), failing the test if it doesn't stop
+// cleanly.
+func stopService(tb testing.TB, s service.Service) {
+	tb.Helper()
+	if err := s.Stop(); err != nil {
+		tb.Error(err)
+	}
+}
+
+// doCommit advances the mempool and the app it's connected to by one height:
+// it removes txs from the mempool as Update would after a block is
+// committed, then commits the app so its state matches.
+func doCommit(tb testing.TB, mp *CListMempool, app abci.Application, txs types.Txs, height int64) {
+	tb.Helper()
+
+	mp.PreUpdate()
+	mp.Lock()
+	err := mp.Update(height, txs, abciResponses(len(txs), abci.CodeTypeOK), nil, nil)
+	mp.Unlock()
+	require.NoError(tb, err)
+
+	_, err = app.Commit(context.Background(), &abci.CommitRequest{})
+	require.NoError(tb, err)
+}
+
+// abciResponses builds n successful (or, with a non-OK code, failed)
+// ExecTxResults, for tests that need to call Update without running real
+// txs through an app.
+func abciResponses(n int, code uint32) []*abci.ExecTxResult {
+	responses := make([]*abci.ExecTxResult, 0, n)
+	for i := 0; i < n; i++ {
+		responses = append(responses, &abci.ExecTxResult{Code: code})
+	}
+	return responses
+}
@@ -0,0 +1,79 @@
+package mempool
+
+import (
+	"sync"
+
+	"github.com/cometbft/cometbft/p2p"
+)
+
+// mempoolIDs is a thread-safe map of peer IDs to short uint16 IDs used to
+// keep track of peer mempool state without storing the full types.NodeID
+// (20 bytes) alongside every tx.
+type mempoolIDs struct {
+	mtx       sync.RWMutex
+	peerMap   map[p2p.ID]uint16
+	nextID    uint16              // assumes it never reaches the full int range
+	activeIDs map[uint16]struct{} // used to check if a given peerID key is used already
+}
+
+// ReserveForPeer searches for the next unused ID and assigns it to the
+// given peer.
+func (ids *mempoolIDs) ReserveForPeer(peer p2p.Peer) {
+	ids.mtx.Lock()
+	defer ids.mtx.Unlock()
+
+	curID := ids.nextPeerID()
+	ids.peerMap[peer.ID()] = curID
+	ids.activeIDs[curID] = struct{}{}
+}
+
+// nextPeerID returns the next unused peer ID to use. It assumes the mutex
+// is already locked.
+func (ids *mempoolIDs) nextPeerID() uint16 {
+	if len(ids.activeIDs) == MaxActiveIDs {
+		panic("node has maximum 65535 active IDs and wanted to get one more")
+	}
+
+	_, idExists := ids.activeIDs[ids.nextID]
+	for idExists {
+		ids.nextID++
+		_, idExists = ids.activeIDs[ids.nextID]
+	}
+	curID := ids.nextID
+	ids.nextID++
+	return curID
+}
+
+// Reclaim releases the ID reserved for the given peer.
+func (ids *mempoolIDs) Reclaim(peer p2p.Peer) {
+	ids.mtx.Lock()
+	defer ids.mtx.Unlock()
+
+	removedID, ok := ids.peerMap[peer.ID()]
+	if ok {
+		delete(ids.activeIDs, removedID)
+		delete(ids.peerMap, peer.ID())
+	}
+}
+
+// GetForPeer returns the ID reserved for the given peer, or 0 if the peer is
+// not known (0 is reserved for the node itself).
+func (ids *mempoolIDs) GetForPeer(peer p2p.Peer) uint16 {
+	ids.mtx.RLock()
+	defer ids.mtx.RUnlock()
+
+	return ids.peerMap[peer.ID()]
+}
+
+func newMempoolIDs() *mempoolIDs {
+	return &mempoolIDs{
+		peerMap:   make(map[p2p.ID]uint16),
+		activeIDs: map[uint16]struct{}{0: {}},
+		nextID:    1, // reserve UnknownPeerID(0) for mempoolReactor.BroadcastTx
+	}
+}
+
+// MaxActiveIDs is the maximum number of "active ids" in the mempoolIDs.
+// This value is tied to the node's use of uint16 to represent peer IDs, and
+// therefore cannot be bumped to be higher than it already is.
+const MaxActiveIDs = 1 << 16
@@ -0,0 +1,141 @@
+package mempool
+
+import (
+	"time"
+
+	abcicli "github.com/cometbft/cometbft/abci/client"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/libs/clist"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// Mempool defines the part of the mempool's surface that the Reactor (and
+// any other would-be caller, such as the consensus state machine) needs.
+// CListMempool is the original, FIFO-per-lane implementation; PriorityMempool
+// is a second implementation backed by a priority queue. cfg.MempoolConfig's
+// Version field selects which one NewMempool constructs.
+type Mempool interface {
+	// CheckTx runs the application's CheckTx on tx and, if accepted, queues
+	// it for gossip and later inclusion in a block.
+	CheckTx(tx types.Tx) (*abcicli.ReqRes, error)
+
+	// ReapMaxBytesMaxGas reaps the transactions that best fit within the
+	// given byte and gas bounds, in the implementation's own priority order.
+	ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs
+
+	// ReapMaxTxs reaps up to max transactions, in the implementation's own
+	// priority order. A negative max means no limit.
+	ReapMaxTxs(max int) types.Txs
+
+	// Update removes the given (now committed) txs from the mempool and
+	// advances it to height.
+	Update(
+		height int64,
+		txs types.Txs,
+		txResults []*abci.ExecTxResult,
+		preCheck PreCheckFunc,
+		postCheck PostCheckFunc,
+	) error
+
+	// Flush removes all transactions from the mempool.
+	Flush()
+
+	// Size returns the number of transactions currently in the mempool.
+	Size() int
+
+	// SizeBytes returns the total size, in bytes, of all txs in the mempool.
+	SizeBytes() int64
+
+	// TxsWaitChan returns a channel that is closed once a new tx becomes
+	// available.
+	TxsWaitChan() <-chan struct{}
+
+	// TxsFront returns the first element of the underlying store, for
+	// callers that only need a single, non-lane-aware cursor.
+	TxsFront() *clist.CElement
+
+	// EnableTxsRemoved enables the channel returned by TxsRemoved.
+	EnableTxsRemoved()
+
+	// TxsRemoved returns a channel of keys removed from the mempool, e.g.
+	// after being committed. EnableTxsRemoved must be called first.
+	TxsRemoved() <-chan types.TxKey
+
+	// Lock/Unlock guard access to the mempool between CheckTx and
+	// Update/Reap.
+	Lock()
+	Unlock()
+
+	// PreUpdate informs the mempool that Update is about to be called, so
+	// it can stop consuming new transactions until Update returns.
+	PreUpdate()
+
+	// FlushAppConn flushes the mempool's connection to the application to
+	// ensure any pending async callbacks have completed.
+	FlushAppConn() error
+
+	// SetLogger sets the Logger used by the mempool.
+	SetLogger(log.Logger)
+
+	// Has reports whether a tx with the given key is already known to the
+	// mempool, used by the Have/Want gossip protocol to decide whether a
+	// key needs to be requested from its announcer.
+	Has(key types.TxKey) bool
+
+	// GetTx returns the tx body for key, if the mempool currently holds it.
+	GetTx(key types.TxKey) (types.Tx, bool)
+}
+
+// LaneAwareMempool is implemented by Mempool implementations that split
+// transactions across multiple gossip lanes (see LaneData). The Reactor
+// uses it, when available, to schedule broadcasting with weighted
+// round-robin across lanes; implementations that don't support lanes (e.g.
+// PriorityMempool) are gossiped through a single, implicit lane instead.
+type LaneAwareMempool interface {
+	Mempool
+
+	// SortedLanes returns every known lane ID, in decreasing priority order.
+	SortedLanes() []types.LaneID
+
+	// LaneFront returns the front element of the given lane's clist, or nil
+	// if the lane is empty or unknown.
+	LaneFront(lane types.LaneID) *clist.CElement
+
+	// LanePriority returns the gossip weight advertised for lane.
+	LanePriority(lane types.LaneID) uint32
+
+	// LaneStats reports the size, total bytes and head-of-line age of lane.
+	LaneStats(lane types.LaneID) (size int, bytes int64, headAge time.Duration)
+}
+
+// PreCheckFunc and PostCheckFunc mirror the checks run before/after CheckTx.
+type (
+	PreCheckFunc  func(types.Tx) error
+	PostCheckFunc func(types.Tx, *abci.CheckTxResponse) error
+)
+
+var (
+	_ Mempool          = (*CListMempool)(nil)
+	_ Mempool          = (*PriorityMempool)(nil)
+	_ LaneAwareMempool = (*CListMempool)(nil)
+)
+
+// NewMempool constructs the Mempool implementation selected by
+// config.Version: "priority" builds a PriorityMempool, anything else
+// (including the empty string) builds the default CListMempool.
+func NewMempool(
+	config *cfg.MempoolConfig,
+	proxyAppConn proxy.AppConnMempool,
+	height int64,
+	laneInfo *LaneData,
+) Mempool {
+	switch config.Version {
+	case "priority":
+		return NewPriorityMempool(config, proxyAppConn, height)
+	default:
+		return NewCListMempool(config, proxyAppConn, height, laneInfo)
+	}
+}
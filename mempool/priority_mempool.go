@@ -0,0 +1,419 @@
+package mempool
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	abcicli "github.com/cometbft/cometbft/abci/client"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/libs/clist"
+	"github.com/cometbft/cometbft/libs/log"
+	cmtsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// priorityTx wraps a tx with the bookkeeping priorityMempool needs to
+// order, rate-limit and replace it.
+type priorityTx struct {
+	tx         types.Tx
+	sender     string
+	priority   int64
+	height     int64
+	gasWanted  int64
+	enqueuedAt time.Time
+
+	heapIndex int             // maintained by txHeap
+	elem      *clist.CElement // this tx's element in the gossip clist
+}
+
+// txHeap is a container/heap max-heap of *priorityTx, ordered by decreasing
+// Priority. It backs ReapMaxBytesMaxGas/ReapMaxTxs's "highest priority
+// first" ordering and per-sender replacement.
+type txHeap []*priorityTx
+
+func (h txHeap) Len() int           { return len(h) }
+func (h txHeap) Less(i, j int) bool { return h[i].priority > h[j].priority }
+func (h txHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex, h[j].heapIndex = i, j
+}
+
+func (h *txHeap) Push(x interface{}) {
+	item := x.(*priorityTx)
+	item.heapIndex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *txHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.heapIndex = -1
+	*h = old[:n-1]
+	return item
+}
+
+// PriorityMempool is a Mempool implementation backed by a max-heap keyed on
+// the Priority field the application returns in ResponseCheckTx, so
+// ReapMaxBytesMaxGas/ReapMaxTxs drain the highest-priority txs first.
+// Sender is used both for per-sender rate limiting (MaxTxsPerSender) and to
+// pick a victim to evict when a sender is over that limit and submits a
+// higher-priority tx.
+//
+// Gossip order is independent of reap order: txs are additionally linked
+// into a clist.CList in arrival order, exposed through TxsFront/TxsWaitChan
+// for the Reactor's (non-lane-aware) broadcast loop.
+type PriorityMempool struct {
+	config       *cfg.MempoolConfig
+	proxyAppConn proxy.AppConnMempool
+
+	mtx      cmtsync.RWMutex
+	height   int64
+	txsBytes int64
+
+	heap     txHeap
+	bySender map[string][]*priorityTx
+	cache    map[types.TxKey]*priorityTx
+
+	gossip *clist.CList
+
+	txsRemoved  chan types.TxKey
+	removedOnce cmtsync.RWMutex
+
+	logger log.Logger
+}
+
+// NewPriorityMempool returns a new priority-queue-backed mempool.
+func NewPriorityMempool(
+	config *cfg.MempoolConfig,
+	proxyAppConn proxy.AppConnMempool,
+	height int64,
+) *PriorityMempool {
+	return &PriorityMempool{
+		config:       config,
+		proxyAppConn: proxyAppConn,
+		height:       height,
+		bySender:     make(map[string][]*priorityTx),
+		cache:        make(map[types.TxKey]*priorityTx),
+		gossip:       clist.New(),
+		logger:       log.NewNopLogger(),
+	}
+}
+
+func (mem *PriorityMempool) SetLogger(l log.Logger) { mem.logger = l }
+func (mem *PriorityMempool) Lock()                  { mem.mtx.Lock() }
+func (mem *PriorityMempool) Unlock()                { mem.mtx.Unlock() }
+func (mem *PriorityMempool) PreUpdate()             {}
+
+func (mem *PriorityMempool) Size() int {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	return len(mem.heap)
+}
+
+func (mem *PriorityMempool) SizeBytes() int64 {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	return mem.txsBytes
+}
+
+func (mem *PriorityMempool) Has(key types.TxKey) bool {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	_, ok := mem.cache[key]
+	return ok
+}
+
+func (mem *PriorityMempool) GetTx(key types.TxKey) (types.Tx, bool) {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	ptx, ok := mem.cache[key]
+	if !ok || ptx == nil {
+		return nil, false
+	}
+	return ptx.tx, true
+}
+
+func (mem *PriorityMempool) TxsFront() *clist.CElement {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	return mem.gossip.Front()
+}
+
+func (mem *PriorityMempool) TxsWaitChan() <-chan struct{} {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+	return mem.gossip.WaitChan()
+}
+
+// CheckTx runs the application's CheckTx and, if accepted, inserts the tx
+// keyed on the Priority and Sender the app returned.
+func (mem *PriorityMempool) CheckTx(tx types.Tx) (*abcicli.ReqRes, error) {
+	mem.mtx.Lock()
+
+	txKey := tx.Key()
+	if _, ok := mem.cache[txKey]; ok {
+		mem.mtx.Unlock()
+		return nil, ErrTxInCache
+	}
+	if len(tx) > mem.config.MaxTxBytes {
+		mem.mtx.Unlock()
+		return nil, ErrTxTooLarge{Max: mem.config.MaxTxBytes, Actual: len(tx)}
+	}
+
+	// Reserve the cache slot synchronously, as a nil placeholder, under the
+	// exclusive lock, before the async CheckTx round-trip. Otherwise two
+	// CheckTx calls for the same tx from two different peers both pass the
+	// dedup check above, and the second checkTxCallback's insert() silently
+	// overwrites the first tx's cache entry, orphaning it in mem.heap forever
+	// (unreachable via cache, so Update/remove can never evict it).
+	mem.cache[txKey] = nil
+	mem.mtx.Unlock()
+
+	reqRes, err := mem.proxyAppConn.CheckTxAsync(context.Background(), &abci.CheckTxRequest{
+		Tx:   tx,
+		Type: abci.CHECK_TX_TYPE_CHECK,
+	})
+	if err != nil {
+		mem.mtx.Lock()
+		delete(mem.cache, txKey)
+		mem.mtx.Unlock()
+		return nil, err
+	}
+	reqRes.SetCallback(mem.checkTxCallback(tx, txKey))
+	return reqRes, nil
+}
+
+func (mem *PriorityMempool) checkTxCallback(tx types.Tx, txKey types.TxKey) func(*abci.Response) {
+	return func(r *abci.Response) {
+		res := r.GetCheckTx()
+		if res == nil || res.Code != abci.CodeTypeOK {
+			// Release the cache reservation CheckTx took out; the tx was
+			// never actually inserted.
+			mem.mtx.Lock()
+			delete(mem.cache, txKey)
+			mem.mtx.Unlock()
+			return
+		}
+
+		mem.mtx.Lock()
+
+		ptx := &priorityTx{
+			tx:         tx,
+			sender:     res.Sender,
+			priority:   res.Priority,
+			height:     mem.height,
+			gasWanted:  res.GasWanted,
+			enqueuedAt: time.Now(),
+		}
+
+		var evicted types.TxKey
+		hasEvicted := false
+		if mem.config.MaxTxsPerSender > 0 && ptx.sender != "" &&
+			len(mem.bySender[ptx.sender]) >= mem.config.MaxTxsPerSender {
+			victimKey, ok := mem.evictForSender(ptx)
+			if !ok {
+				// Sender is at its limit and every one of its txs is at
+				// least as important as this one: reject, releasing the
+				// reservation.
+				delete(mem.cache, txKey)
+				mem.mtx.Unlock()
+				return
+			}
+			evicted, hasEvicted = victimKey, true
+		}
+
+		mem.insert(ptx)
+		mem.mtx.Unlock()
+
+		// Notify after releasing mtx: notifyTxRemoved may block for up to a
+		// second on a slow TxsRemoved consumer, and must not hold up every
+		// other CheckTx/Reap*/Size call while it does.
+		if hasEvicted {
+			mem.notifyTxRemoved(evicted)
+		}
+	}
+}
+
+// evictForSender removes the lowest-priority tx belonging to ptx.sender if
+// ptx outranks it, making room for ptx. Returns the evicted tx's key and
+// whether room was made; the caller notifies TxsRemoved once mem.mtx is
+// released.
+func (mem *PriorityMempool) evictForSender(ptx *priorityTx) (types.TxKey, bool) {
+	senderTxs := mem.bySender[ptx.sender]
+
+	victim := senderTxs[0]
+	for _, t := range senderTxs[1:] {
+		if t.priority < victim.priority {
+			victim = t
+		}
+	}
+	if victim.priority >= ptx.priority {
+		return types.TxKey{}, false
+	}
+
+	mem.remove(victim)
+	return victim.tx.Key(), true
+}
+
+func (mem *PriorityMempool) insert(ptx *priorityTx) {
+	heap.Push(&mem.heap, ptx)
+	mem.bySender[ptx.sender] = append(mem.bySender[ptx.sender], ptx)
+	mem.cache[ptx.tx.Key()] = ptx
+	ptx.elem = mem.gossip.PushBack(&mempoolTx{
+		height:     ptx.height,
+		gasWanted:  ptx.gasWanted,
+		tx:         ptx.tx,
+		enqueuedAt: ptx.enqueuedAt,
+	})
+	mem.txsBytes += int64(len(ptx.tx))
+}
+
+// remove evicts ptx from every index. It does not notify TxsRemoved; callers
+// are responsible for that once they've released mem.mtx, since
+// notifyTxRemoved can block on a slow consumer and must not do so while
+// holding the exclusive lock.
+func (mem *PriorityMempool) remove(ptx *priorityTx) {
+	heap.Remove(&mem.heap, ptx.heapIndex)
+	delete(mem.cache, ptx.tx.Key())
+	mem.txsBytes -= int64(len(ptx.tx))
+	mem.gossip.Remove(ptx.elem)
+	ptx.elem.DetachPrev()
+
+	senderTxs := mem.bySender[ptx.sender]
+	for i, t := range senderTxs {
+		if t == ptx {
+			mem.bySender[ptx.sender] = append(senderTxs[:i], senderTxs[i+1:]...)
+			break
+		}
+	}
+	if len(mem.bySender[ptx.sender]) == 0 {
+		delete(mem.bySender, ptx.sender)
+	}
+}
+
+// ReapMaxBytesMaxGas drains the heap highest-priority-first, without
+// removing anything, until either bound would be exceeded.
+func (mem *PriorityMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	sorted := mem.sortedByPriority()
+	var totalBytes, totalGas int64
+	txs := make([]types.Tx, 0, len(sorted))
+	for _, ptx := range sorted {
+		totalBytes += int64(len(ptx.tx))
+		if maxBytes > -1 && totalBytes > maxBytes {
+			break
+		}
+		totalGas += ptx.gasWanted
+		if maxGas > -1 && totalGas > maxGas {
+			break
+		}
+		txs = append(txs, ptx.tx)
+	}
+	return txs
+}
+
+// ReapMaxTxs returns up to max txs, highest priority first.
+func (mem *PriorityMempool) ReapMaxTxs(max int) types.Txs {
+	mem.mtx.RLock()
+	defer mem.mtx.RUnlock()
+
+	sorted := mem.sortedByPriority()
+	if max < 0 || max > len(sorted) {
+		max = len(sorted)
+	}
+	txs := make([]types.Tx, max)
+	for i := 0; i < max; i++ {
+		txs[i] = sorted[i].tx
+	}
+	return txs
+}
+
+// sortedByPriority returns every tx in decreasing priority order without
+// mutating mem.heap. Assumes the caller holds (at least) a read lock.
+func (mem *PriorityMempool) sortedByPriority() []*priorityTx {
+	cp := make(txHeap, len(mem.heap))
+	copy(cp, mem.heap)
+	sorted := make([]*priorityTx, 0, len(cp))
+	for cp.Len() > 0 {
+		sorted = append(sorted, heap.Pop(&cp).(*priorityTx))
+	}
+	return sorted
+}
+
+// Update removes committed txs from the mempool.
+func (mem *PriorityMempool) Update(
+	height int64,
+	txs types.Txs,
+	_ []*abci.ExecTxResult,
+	_ PreCheckFunc,
+	_ PostCheckFunc,
+) error {
+	mem.mtx.Lock()
+
+	mem.height = height
+	var removed []types.TxKey
+	for _, tx := range txs {
+		if ptx, ok := mem.cache[tx.Key()]; ok && ptx != nil {
+			mem.remove(ptx)
+			removed = append(removed, tx.Key())
+		}
+	}
+	mem.mtx.Unlock()
+
+	// Notify after releasing mtx: notifyTxRemoved may block for up to a
+	// second on a slow TxsRemoved consumer, and must not hold up every other
+	// CheckTx/Reap*/Size call while it does.
+	for _, key := range removed {
+		mem.notifyTxRemoved(key)
+	}
+	return nil
+}
+
+func (mem *PriorityMempool) Flush() {
+	mem.mtx.Lock()
+	defer mem.mtx.Unlock()
+
+	mem.heap = nil
+	mem.bySender = make(map[string][]*priorityTx)
+	mem.cache = make(map[types.TxKey]*priorityTx)
+	mem.gossip = clist.New()
+	mem.txsBytes = 0
+}
+
+func (mem *PriorityMempool) FlushAppConn() error {
+	return mem.proxyAppConn.Flush(context.Background())
+}
+
+func (mem *PriorityMempool) EnableTxsRemoved() {
+	mem.removedOnce.Lock()
+	defer mem.removedOnce.Unlock()
+	if mem.txsRemoved == nil {
+		mem.txsRemoved = make(chan types.TxKey, 100)
+	}
+}
+
+func (mem *PriorityMempool) TxsRemoved() <-chan types.TxKey {
+	mem.removedOnce.RLock()
+	defer mem.removedOnce.RUnlock()
+	return mem.txsRemoved
+}
+
+func (mem *PriorityMempool) notifyTxRemoved(key types.TxKey) {
+	mem.removedOnce.RLock()
+	defer mem.removedOnce.RUnlock()
+	if mem.txsRemoved == nil {
+		return
+	}
+	select {
+	case mem.txsRemoved <- key:
+	case <-time.After(time.Second):
+		mem.logger.Error("txsRemoved channel is full, dropping notification", "tx", key)
+	}
+}
@@ -0,0 +1,128 @@
+package mempool
+
+import (
+	"container/heap"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/abci/example/kvstore"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// TestTxHeapOrdering checks that txHeap pops in decreasing priority order,
+// independent of insertion order, which ReapMaxBytesMaxGas/ReapMaxTxs rely
+// on via sortedByPriority.
+func TestTxHeapOrdering(t *testing.T) {
+	h := &txHeap{}
+	heap.Init(h)
+
+	priorities := []int64{5, 1, 100, 42, 7}
+	for _, p := range priorities {
+		heap.Push(h, &priorityTx{tx: types.Tx("tx"), priority: p})
+	}
+
+	var got []int64
+	for h.Len() > 0 {
+		got = append(got, heap.Pop(h).(*priorityTx).priority)
+	}
+
+	require.Equal(t, []int64{100, 42, 7, 5, 1}, got)
+}
+
+// TestBoundedKeySetEviction checks that boundedKeySet forgets its oldest
+// entries once it reaches capacity, rather than growing without bound.
+func TestBoundedKeySetEviction(t *testing.T) {
+	set := newBoundedKeySet(2)
+
+	var keys []types.TxKey
+	for _, tx := range []types.Tx{"a", "b", "c"} {
+		keys = append(keys, tx.Key())
+	}
+
+	require.True(t, set.Add(keys[0]))
+	require.True(t, set.Add(keys[1]))
+	require.True(t, set.Has(keys[0]))
+
+	// Adding a third key evicts the oldest (keys[0]).
+	require.True(t, set.Add(keys[2]))
+	require.False(t, set.Has(keys[0]))
+	require.True(t, set.Has(keys[1]))
+	require.True(t, set.Has(keys[2]))
+
+	// Re-adding an already-tracked key is a no-op, not a new entry.
+	require.False(t, set.Add(keys[1]))
+}
+
+// newMempoolAppConn bootstraps a real (in-process) ABCI connection to app,
+// reusing the same client-connection setup as newMempoolWithApp so both
+// mempool implementations can be driven against a real app without every
+// test needing its own copy of that bootstrap.
+func newMempoolAppConn(t *testing.T, cc proxy.ClientCreator) proxy.AppConnMempool {
+	t.Helper()
+	mp, cleanup := newMempoolWithApp(cc)
+	t.Cleanup(cleanup)
+	return mp.proxyAppConn
+}
+
+// TestMempoolImplementationsAgreeOnLifecycle runs the same
+// CheckTx -> ReapMaxBytesMaxGas -> Update lifecycle against both Mempool
+// implementations through the shared interface, so a regression in either
+// one's plumbing (as opposed to its ordering policy) shows up here instead
+// of only in implementation-specific tests.
+func TestMempoolImplementationsAgreeOnLifecycle(t *testing.T) {
+	testCases := []struct {
+		name    string
+		version string
+	}{
+		{name: "CListMempool", version: ""},
+		{name: "PriorityMempool", version: "priority"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := kvstore.NewInMemoryApplication()
+			cc := proxy.NewLocalClientCreator(app)
+			appConnMem := newMempoolAppConn(t, cc)
+
+			config := &cfg.MempoolConfig{Version: tc.version, Size: 1000, MaxTxBytes: 1024}
+			mp := NewMempool(config, appConnMem, 0, nil)
+			mp.SetLogger(log.TestingLogger())
+			mp.EnableTxsRemoved()
+
+			const numTxs = 5
+			var txs types.Txs
+			for i := 0; i < numTxs; i++ {
+				tx := kvstore.NewTxFromID(i)
+				reqRes, err := mp.CheckTx(tx)
+				require.NoError(t, err)
+				reqRes.Wait()
+				txs = append(txs, tx)
+			}
+			require.NoError(t, mp.FlushAppConn())
+			require.Equal(t, numTxs, mp.Size())
+			for _, tx := range txs {
+				require.True(t, mp.Has(tx.Key()))
+			}
+
+			reaped := mp.ReapMaxBytesMaxGas(-1, -1)
+			require.Len(t, reaped, numTxs)
+
+			mp.PreUpdate()
+			mp.Lock()
+			err := mp.Update(1, reaped, abciResponses(len(reaped), abci.CodeTypeOK), nil, nil)
+			mp.Unlock()
+			require.NoError(t, err)
+
+			require.Equal(t, 0, mp.Size())
+			require.Equal(t, int64(0), mp.SizeBytes())
+			for _, tx := range reaped {
+				require.False(t, mp.Has(tx.Key()))
+			}
+		})
+	}
+}
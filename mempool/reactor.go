@@ -1,7 +1,9 @@
 package mempool
 
 import (
+	"container/list"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"fmt"
@@ -15,13 +17,34 @@ import (
 	"github.com/cometbft/cometbft/types"
 )
 
+// peerFanoutQueueSize bounds how many txs the dispatcher will queue for a
+// single peer before it starts dropping sends for that peer rather than
+// blocking the shared cursor.
+const peerFanoutQueueSize = 1000
+
+// maxTxSenders bounds how many tx keys txSenders tracks sender sets for.
+// Entries for txs that actually land in the mempool are cleaned up promptly
+// via updateSendersRoutine, long before this limit matters; the bound exists
+// because handleTxAnnounce also adds an entry for every announced key before
+// we know whether the tx is real (see GossipMode pull/hybrid), and a key is
+// attacker-controlled 32 bytes a peer can announce without ever following up
+// with the body. Once full, the oldest entry is evicted to make room, same
+// as announcedTo's boundedKeySet.
+const maxTxSenders = 100_000
+
 // Reactor handles mempool tx broadcasting amongst peers.
 // It maintains a map from peer ID to counter, to prevent gossiping txs to the
 // peers you received it from.
+//
+// A single dispatcher goroutine walks the mempool's lanes once and fans new
+// txs out to every peer's bounded queue; a lightweight per-peer goroutine
+// drains its own queue, applying the lag check and batching before sending.
+// This avoids giving every peer its own live clist cursor, and keeps one
+// slow peer from holding up anyone else.
 type Reactor struct {
 	p2p.BaseReactor
 	config  *cfg.MempoolConfig
-	mempool *CListMempool
+	mempool Mempool
 	ids     *mempoolIDs
 
 	// `txSenders` maps every received transaction to the set of peer IDs that
@@ -32,15 +55,49 @@ type Reactor struct {
 	// of 20 bytes for the types.NodeID.
 	txSenders    map[types.TxKey]map[uint16]bool
 	txSendersMtx cmtsync.RWMutex
+
+	// txSenderOrder and txSenderElems track insertion order for txSenders so
+	// addSender can evict the oldest entry once maxTxSenders is reached.
+	// Lazily initialized so Reactor values built directly (as some tests do)
+	// without going through NewReactor still behave correctly.
+	txSenderOrder *list.List
+	txSenderElems map[types.TxKey]*list.Element
+
+	// peers holds the fanout queue for every currently connected peer,
+	// keyed by its short mempool peer ID.
+	peers    map[uint16]*peerFanout
+	peersMtx cmtsync.RWMutex
+
+	// fanoutDropped counts sends the dispatcher dropped because a peer's
+	// queue was full, for observability. Accessed atomically.
+	fanoutDropped uint64
+
+	// sendFailed counts p2p sends (batched txs or announces) that a peer's
+	// Send reported as failed, for observability. Accessed atomically. The
+	// p2p layer owns reconnection/backoff for the underlying connection, so
+	// the mempool doesn't retry these itself; it just stops pretending the
+	// tx was delivered.
+	sendFailed uint64
+
+	// announce holds the Have/Want bookkeeping used when config.GossipMode
+	// is "pull" or "hybrid". It is unused (but always initialized) in
+	// "push" mode.
+	announce *announceState
 }
 
-// NewReactor returns a new Reactor with the given config and mempool.
-func NewReactor(config *cfg.MempoolConfig, mempool *CListMempool) *Reactor {
+// NewReactor returns a new Reactor with the given config and mempool. Any
+// Mempool implementation may be used; cfg.MempoolConfig.Version ("v0" or
+// "priority") determines which one a node constructs in practice.
+func NewReactor(config *cfg.MempoolConfig, mempool Mempool) *Reactor {
 	memR := &Reactor{
-		config:    config,
-		mempool:   mempool,
-		ids:       newMempoolIDs(),
-		txSenders: make(map[types.TxKey]map[uint16]bool),
+		config:        config,
+		mempool:       mempool,
+		ids:           newMempoolIDs(),
+		txSenders:     make(map[types.TxKey]map[uint16]bool),
+		txSenderOrder: list.New(),
+		txSenderElems: make(map[types.TxKey]*list.Element),
+		peers:         make(map[uint16]*peerFanout),
+		announce:      newAnnounceState(),
 	}
 	memR.BaseReactor = *p2p.NewBaseReactor("Mempool", memR)
 	return memR
@@ -67,16 +124,30 @@ func (memR *Reactor) OnStart() error {
 	memR.mempool.EnableTxsRemoved()
 	go memR.updateSendersRoutine()
 
+	if memR.gossipModeFor() != GossipModePush {
+		go memR.pullTxRoutine()
+	}
+
+	if memR.config.Broadcast {
+		go memR.dispatchRoutine()
+	}
+
 	return nil
 }
 
 // GetChannels implements Reactor by returning the list of channels for this
 // reactor.
 func (memR *Reactor) GetChannels() []*p2p.ChannelDescriptor {
-	largestTx := make([]byte, memR.config.MaxTxBytes)
+	// RecvMessageCapacity must cover the largest message we ourselves may
+	// send: either a batch of BroadcastBatchBytes worth of tx bodies, or a
+	// single tx up to MaxTxBytes pushed on its own (e.g. the batch flushed
+	// early because the next tx wouldn't fit, or a request response).
+	// BroadcastBatchBytes and MaxTxBytes are independent config knobs, so
+	// sizing off either alone can be smaller than a legitimate message.
+	largestBatch := make([]byte, maxInt64(memR.config.MaxTxBytes, memR.config.BroadcastBatchBytes))
 	batchMsg := protomem.Message{
 		Sum: &protomem.Message_Txs{
-			Txs: &protomem.Txs{Txs: [][]byte{largestTx}},
+			Txs: &protomem.Txs{Txs: [][]byte{largestBatch}},
 		},
 	}
 
@@ -90,18 +161,48 @@ func (memR *Reactor) GetChannels() []*p2p.ChannelDescriptor {
 	}
 }
 
+// maxInt64 returns the larger of a and b as an int64. It takes a as an int
+// (config.MaxTxBytes) and b as an int64 (BroadcastBatchBytes), matching the
+// types of the two config fields it compares.
+func maxInt64(a int, b int64) int64 {
+	if int64(a) > b {
+		return int64(a)
+	}
+	return b
+}
+
 // AddPeer implements Reactor.
-// It starts a broadcast routine ensuring all txs are forwarded to the given peer.
+// It registers the peer's fanout queue and starts the (lightweight) sender
+// routine that drains it; the shared dispatcher routine handles deciding
+// what goes into that queue.
 func (memR *Reactor) AddPeer(peer p2p.Peer) {
-	if memR.config.Broadcast {
-		go memR.broadcastTxRoutine(peer)
+	if !memR.config.Broadcast {
+		return
+	}
+
+	pf := &peerFanout{
+		peer:   peer,
+		peerID: memR.ids.GetForPeer(peer),
+		txs:    make(chan *mempoolTx, peerFanoutQueueSize),
 	}
+
+	memR.peersMtx.Lock()
+	memR.peers[pf.peerID] = pf
+	memR.peersMtx.Unlock()
+
+	go memR.peerSendRoutine(pf)
 }
 
 // RemovePeer implements Reactor.
 func (memR *Reactor) RemovePeer(peer p2p.Peer, _ interface{}) {
+	peerID := memR.ids.GetForPeer(peer)
+
+	memR.peersMtx.Lock()
+	delete(memR.peers, peerID)
+	memR.peersMtx.Unlock()
+
 	memR.ids.Reclaim(peer)
-	// broadcast routine checks if peer is gone and returns
+	// sender routine checks if peer is gone and returns
 }
 
 // Receive implements Reactor.
@@ -125,6 +226,7 @@ func (memR *Reactor) Receive(e p2p.Envelope) {
 			// Note that it's possible a tx is still in the cache but no longer in the mempool.
 			// For example, after committing a block, txs are removed from mempool but not the cache.
 			memR.addSender(tx.Key(), memR.ids.GetForPeer(e.Src))
+			memR.onTxReceived(tx.Key())
 
 			_, err = memR.mempool.CheckTx(tx)
 			if errors.Is(err, ErrTxInCache) {
@@ -133,6 +235,10 @@ func (memR *Reactor) Receive(e p2p.Envelope) {
 				memR.Logger.Info("Could not check tx", "tx", tx.String(), "err", err)
 			}
 		}
+	case *protomem.TxAnnounce:
+		memR.handleTxAnnounce(e.Src, msg)
+	case *protomem.TxRequest:
+		memR.handleTxRequest(e.Src, msg)
 	default:
 		memR.Logger.Error("unknown message type", "src", e.Src, "chId", e.ChannelID, "msg", e.Message)
 		memR.Switch.StopPeerForError(e.Src, fmt.Errorf("mempool cannot handle message of type: %T", e.Message))
@@ -147,75 +253,306 @@ type PeerState interface {
 	GetHeight() int64
 }
 
-// Send new mempool txs to peer.
-func (memR *Reactor) broadcastTxRoutine(peer p2p.Peer) {
-	peerID := memR.ids.GetForPeer(peer)
-	var next *clist.CElement
+// laneSource abstracts the lane-aware scheduling primitives dispatchRoutine
+// needs, so it can drive either a real LaneAwareMempool (multiple weighted
+// lanes) or a plain Mempool (treated as a single, implicit lane of priority
+// 1) with the same deficit-round-robin loop.
+type laneSource interface {
+	sortedLanes() []types.LaneID
+	front(lane types.LaneID) *clist.CElement
+	priority(lane types.LaneID) uint32
+	waitChan() <-chan struct{}
+}
+
+type laneAwareSource struct{ mem LaneAwareMempool }
+
+func (s laneAwareSource) sortedLanes() []types.LaneID             { return s.mem.SortedLanes() }
+func (s laneAwareSource) front(lane types.LaneID) *clist.CElement { return s.mem.LaneFront(lane) }
+func (s laneAwareSource) priority(lane types.LaneID) uint32       { return s.mem.LanePriority(lane) }
+func (s laneAwareSource) waitChan() <-chan struct{}               { return s.mem.TxsWaitChan() }
+
+// singleQueueSource adapts a plain Mempool (no lane support) into a
+// laneSource with exactly one implicit lane, so implementations like
+// PriorityMempool are still gossiped through the same scheduling loop.
+type singleQueueSource struct{ mem Mempool }
+
+func (s singleQueueSource) sortedLanes() []types.LaneID        { return []types.LaneID{defaultLaneID} }
+func (s singleQueueSource) front(types.LaneID) *clist.CElement { return s.mem.TxsFront() }
+func (s singleQueueSource) priority(types.LaneID) uint32       { return 1 }
+func (s singleQueueSource) waitChan() <-chan struct{}          { return s.mem.TxsWaitChan() }
+
+func (memR *Reactor) laneSource() laneSource {
+	if laneMem, ok := memR.mempool.(LaneAwareMempool); ok {
+		return laneAwareSource{laneMem}
+	}
+	return singleQueueSource{memR.mempool}
+}
+
+// laneCursor tracks where the dispatch routine is within one lane's clist,
+// plus its deficit round-robin credit counter.
+type laneCursor struct {
+	lane    types.LaneID
+	next    *clist.CElement
+	deficit uint32
+
+	// waiting is set to next's NextWaitChan() when the element we just sent
+	// has no successor yet. While set, this lane is skipped entirely (no
+	// re-crediting, no re-sending c.next) until the channel fires, since
+	// c.next stays non-nil and would otherwise look identical to a freshly
+	// reset cursor and be resent every round.
+	waiting <-chan struct{}
+}
+
+// txBatch accumulates consecutive pushed txs into a single protomem.Txs
+// message, re-enabling the batching the reactor used to do (per peer)
+// before it was disabled for tendermint/tendermint#5796. byteSize is kept
+// up to date incrementally so flush decisions don't need to re-marshal the
+// batch just to measure it.
+type txBatch struct {
+	txs      [][]byte
+	byteSize int
+	started  time.Time
+}
+
+func (b *txBatch) add(tx []byte) {
+	if len(b.txs) == 0 {
+		b.started = time.Now()
+	}
+	b.txs = append(b.txs, tx)
+	b.byteSize += len(tx)
+}
+
+func (b *txBatch) empty() bool {
+	return len(b.txs) == 0
+}
+
+// full reports whether any of the configured batch bounds have been
+// reached, i.e. the batch should be flushed before adding anything more.
+func (b *txBatch) full(config *cfg.MempoolConfig) bool {
+	if b.empty() {
+		return false
+	}
+	return len(b.txs) >= config.BroadcastBatchSize ||
+		int64(b.byteSize) >= config.BroadcastBatchBytes ||
+		time.Since(b.started) >= config.BroadcastBatchTimeout
+}
+
+func (b *txBatch) reset() {
+	b.txs = nil
+	b.byteSize = 0
+}
+
+// peerFanout is a single peer's bounded queue of txs the dispatcher has
+// decided are worth sending it, plus the lightweight goroutine draining it.
+type peerFanout struct {
+	peer   p2p.Peer
+	peerID uint16
+	txs    chan *mempoolTx
+}
+
+// dispatchRoutine is the single goroutine that walks the mempool's lanes and
+// fans new txs out to every connected peer's queue. It replaces the old
+// design of one broadcastTxRoutine (and one live clist cursor) per peer.
+//
+// Lanes are drained using deficit round robin: every round each lane is
+// credited `priority` sendable txs (its "deficit"), highest-priority lane
+// first, and may send up to that many txs before control moves to the next
+// lane. A lane that has nothing to send simply forfeits its credit for the
+// round rather than blocking the others.
+func (memR *Reactor) dispatchRoutine() {
+	lanes := memR.laneSource()
+
+	cursors := make([]*laneCursor, len(lanes.sortedLanes()))
+	for i, lane := range lanes.sortedLanes() {
+		cursors[i] = &laneCursor{lane: lane}
+	}
 
 	for {
-		// In case of both next.NextWaitChan() and peer.Quit() are variable at the same time
-		if !memR.IsRunning() || !peer.IsRunning() {
+		if !memR.IsRunning() {
 			return
 		}
-		// This happens because the CElement we were looking at got garbage
-		// collected (removed). That is, .NextWait() returned nil. Go ahead and
-		// start from the beginning.
-		if next == nil {
-			select {
-			case <-memR.mempool.TxsWaitChan(): // Wait until a tx is available
-				if next = memR.mempool.TxsFront(); next == nil {
+
+		sentAny := false
+		for _, c := range cursors {
+			if c.next == nil {
+				if c.next = lanes.front(c.lane); c.next == nil {
 					continue
 				}
-			case <-peer.Quit():
-				return
-			case <-memR.Quit():
-				return
+			}
+
+			if c.waiting != nil {
+				select {
+				case <-c.waiting:
+					c.waiting = nil
+				default:
+					// Still waiting on a successor for the element we last
+					// sent: don't re-credit or re-send it this round.
+					continue
+				}
+			}
+
+			c.deficit += lanes.priority(c.lane)
+
+		sendLoop:
+			for c.next != nil && c.deficit > 0 {
+				if !memR.IsRunning() {
+					return
+				}
+
+				memTx := c.next.Value.(*mempoolTx)
+				memR.fanout(memTx)
+				c.deficit--
+				sentAny = true
+
+				select {
+				case <-c.next.NextWaitChan():
+					c.next = c.next.Next()
+				default:
+					// The element we just sent has no successor yet: leave
+					// c.next pointing at it (NOT nil, which would make the
+					// next round's lanes.front() hand us the same element
+					// again), remember its NextWaitChan so the top of this
+					// loop skips re-crediting/re-sending it until a
+					// successor actually shows up, and yield the rest of
+					// this round to the next lane.
+					c.waiting = c.next.NextWaitChan()
+					break sendLoop
+				}
 			}
 		}
 
-		// Make sure the peer is up to date.
-		peerState, ok := peer.Get(types.PeerStateKey).(PeerState)
-		if !ok {
-			// Peer does not have a state yet. We set it in the consensus reactor, but
-			// when we add peer in Switch, the order we call reactors#AddPeer is
-			// different every time due to us using a map. Sometimes other reactors
-			// will be initialized before the consensus reactor. We should wait a few
-			// milliseconds and retry.
-			time.Sleep(PeerCatchupSleepIntervalMS * time.Millisecond)
+		if sentAny {
 			continue
 		}
 
-		// If we suspect that the peer is lagging behind, at least by more than
-		// one block, we don't send the transaction immediately. This code
-		// reduces the mempool size and the recheck-tx rate of the receiving
-		// node. See [RFC 103] for an analysis on this optimization.
-		//
-		// [RFC 103]: https://github.com/cometbft/cometbft/pull/735
-		memTx := next.Value.(*mempoolTx)
-		if peerState.GetHeight() < memTx.Height()-1 {
-			time.Sleep(PeerCatchupSleepIntervalMS * time.Millisecond)
+		// Nothing was sent this round across any lane: wait for either a new
+		// tx to show up or for the reactor to stop.
+		waitCh := lanes.waitChan()
+		for _, c := range cursors {
+			if c.waiting != nil {
+				// lanes.waitChan() stays closed while its lane holds any tx,
+				// so wait for the successor of the element last sent instead.
+				waitCh = c.waiting
+				break
+			}
+		}
+		select {
+		case <-waitCh:
+		case <-memR.Quit():
+			return
+		case <-time.After(PeerCatchupSleepIntervalMS * time.Millisecond):
+		}
+	}
+}
+
+// fanout offers memTx to every connected peer's queue, skipping peers we
+// already know have the tx. A peer whose queue is full simply misses this
+// tx rather than stalling the shared cursor; it picks up later ones. The
+// dropped send is counted rather than retried, since retrying would require
+// blocking dispatchRoutine on a specific slow peer.
+func (memR *Reactor) fanout(memTx *mempoolTx) {
+	key := memTx.tx.Key()
+
+	memR.peersMtx.RLock()
+	defer memR.peersMtx.RUnlock()
+
+	for peerID, pf := range memR.peers {
+		if memR.isSender(key, peerID) {
 			continue
 		}
+		select {
+		case pf.txs <- memTx:
+		default:
+			atomic.AddUint64(&memR.fanoutDropped, 1)
+		}
+	}
+}
 
-		// NOTE: Transaction batching was disabled due to
-		// https://github.com/tendermint/tendermint/issues/5796
+// FanoutDropped returns the number of tx sends dropped so far because a
+// peer's fanout queue was full, for observability (e.g. a metrics exporter).
+func (memR *Reactor) FanoutDropped() uint64 {
+	return atomic.LoadUint64(&memR.fanoutDropped)
+}
 
-		if !memR.isSender(memTx.tx.Key(), peerID) {
-			success := peer.Send(p2p.Envelope{
-				ChannelID: MempoolChannel,
-				Message:   &protomem.Txs{Txs: [][]byte{memTx.tx}},
-			})
-			if !success {
-				time.Sleep(PeerCatchupSleepIntervalMS * time.Millisecond)
-				continue
-			}
+// SendFailed returns the number of batched-tx or announce sends that failed
+// so far, for observability (e.g. a metrics exporter).
+func (memR *Reactor) SendFailed() uint64 {
+	return atomic.LoadUint64(&memR.sendFailed)
+}
+
+// peerSendRoutine drains a single peer's fanout queue: it applies the
+// lag check that the old per-peer broadcastTxRoutine did inline, then
+// either batches the tx for a direct push or sends an announce, depending
+// on the configured gossip mode.
+func (memR *Reactor) peerSendRoutine(pf *peerFanout) {
+	batch := &txBatch{}
+	flush := func() bool {
+		if batch.empty() {
+			return true
 		}
+		success := pf.peer.Send(p2p.Envelope{
+			ChannelID: MempoolChannel,
+			Message:   &protomem.Txs{Txs: batch.txs},
+		})
+		if !success {
+			atomic.AddUint64(&memR.sendFailed, 1)
+		}
+		batch.reset()
+		return success
+	}
 
+	flushTimer := time.NewTimer(memR.config.BroadcastBatchTimeout)
+	defer flushTimer.Stop()
+
+	for {
 		select {
-		case <-next.NextWaitChan():
-			// see the start of the for loop for nil check
-			next = next.Next()
-		case <-peer.Quit():
+		case memTx, ok := <-pf.txs:
+			if !ok {
+				return
+			}
+			if !memR.IsRunning() || !pf.peer.IsRunning() {
+				return
+			}
+
+			peerState, ok := pf.peer.Get(types.PeerStateKey).(PeerState)
+			if !ok {
+				// Peer does not have a state yet. We set it in the consensus reactor, but
+				// when we add peer in Switch, the order we call reactors#AddPeer is
+				// different every time due to us using a map. Sometimes other reactors
+				// will be initialized before the consensus reactor. Skip this tx; a later
+				// one will find the state set.
+				continue
+			}
+
+			// If we suspect that the peer is lagging behind, at least by more than
+			// one block, we don't send the transaction. This code reduces the
+			// mempool size and the recheck-tx rate of the receiving node, without
+			// holding up any other peer's queue. See [RFC 103] for an analysis on
+			// this optimization.
+			//
+			// [RFC 103]: https://github.com/cometbft/cometbft/pull/735
+			if peerState.GetHeight() < memTx.Height()-1 {
+				continue
+			}
+
+			if memR.shouldPush(len(memTx.tx)) {
+				if batch.full(memR.config) {
+					flush()
+				}
+				batch.add(memTx.tx)
+				if batch.full(memR.config) {
+					flush()
+				}
+			} else {
+				flush()
+				if !memR.sendAnnounce(pf.peer, memTx.tx.Key()) {
+					atomic.AddUint64(&memR.sendFailed, 1)
+				}
+			}
+		case <-flushTimer.C:
+			flush()
+			flushTimer.Reset(memR.config.BroadcastBatchTimeout)
+		case <-pf.peer.Quit():
 			return
 		case <-memR.Quit():
 			return
@@ -240,7 +577,21 @@ func (memR *Reactor) addSender(txKey types.TxKey, senderID uint16) bool {
 		memR.txSenders[txKey] = sendersSet
 		return false
 	}
+
+	if memR.txSenderOrder == nil {
+		memR.txSenderOrder = list.New()
+		memR.txSenderElems = make(map[types.TxKey]*list.Element)
+	}
+	if memR.txSenderOrder.Len() >= maxTxSenders {
+		if oldest := memR.txSenderOrder.Front(); oldest != nil {
+			oldKey := memR.txSenderOrder.Remove(oldest).(types.TxKey)
+			delete(memR.txSenders, oldKey)
+			delete(memR.txSenderElems, oldKey)
+		}
+	}
+
 	memR.txSenders[txKey] = map[uint16]bool{senderID: true}
+	memR.txSenderElems[txKey] = memR.txSenderOrder.PushBack(txKey)
 	return true
 }
 
@@ -249,6 +600,51 @@ func (memR *Reactor) removeSenders(txKey types.TxKey) {
 	defer memR.txSendersMtx.Unlock()
 
 	delete(memR.txSenders, txKey)
+	if elem, ok := memR.txSenderElems[txKey]; ok {
+		memR.txSenderOrder.Remove(elem)
+		delete(memR.txSenderElems, txKey)
+	}
+}
+
+// LaneInfo reports, for every lane known to the mempool, its priority,
+// current size, total bytes queued and the age of the tx at its head. Lanes
+// are returned in decreasing priority order.
+type LaneInfo struct {
+	Lane     types.LaneID
+	Priority uint32
+	Size     int
+	Bytes    int64
+	HeadAge  time.Duration
+}
+
+// LaneInfo returns a point-in-time snapshot of every gossip lane, for
+// observability (e.g. a status RPC endpoint or metrics exporter). Mempool
+// implementations that don't support lanes are reported as a single
+// implicit lane.
+func (memR *Reactor) LaneInfo() []LaneInfo {
+	laneMem, ok := memR.mempool.(LaneAwareMempool)
+	if !ok {
+		return []LaneInfo{{
+			Lane:     defaultLaneID,
+			Priority: 1,
+			Size:     memR.mempool.Size(),
+			Bytes:    memR.mempool.SizeBytes(),
+		}}
+	}
+
+	ids := laneMem.SortedLanes()
+	info := make([]LaneInfo, len(ids))
+	for i, lane := range ids {
+		size, bytes, headAge := laneMem.LaneStats(lane)
+		info[i] = LaneInfo{
+			Lane:     lane,
+			Priority: laneMem.LanePriority(lane),
+			Size:     size,
+			Bytes:    bytes,
+			HeadAge:  headAge,
+		}
+	}
+	return info
 }
 
 func (memR *Reactor) updateSendersRoutine() {
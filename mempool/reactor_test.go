@@ -0,0 +1,113 @@
+package mempool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/abci/example/kvstore"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/p2p"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// fakePeer is a minimal p2p.Peer that records every envelope sent to it, for
+// tests that need to assert on what the reactor pushed rather than merely
+// that it didn't block or panic. Embedding p2p.Peer promotes the methods we
+// don't care about (they're never called by the reactor code these tests
+// exercise).
+type fakePeer struct {
+	p2p.Peer
+	id p2p.ID
+
+	mtx  sync.Mutex
+	data map[string]interface{}
+	sent []p2p.Envelope
+
+	quitCh chan struct{}
+}
+
+func newFakePeer(id p2p.ID) *fakePeer {
+	return &fakePeer{
+		id:     id,
+		data:   make(map[string]interface{}),
+		quitCh: make(chan struct{}),
+	}
+}
+
+func (p *fakePeer) ID() p2p.ID            { return p.id }
+func (p *fakePeer) IsRunning() bool       { return true }
+func (p *fakePeer) Quit() <-chan struct{} { return p.quitCh }
+
+func (p *fakePeer) Get(key string) interface{} {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.data[key]
+}
+
+func (p *fakePeer) Set(key string, value interface{}) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.data[key] = value
+}
+
+func (p *fakePeer) Send(e p2p.Envelope) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.sent = append(p.sent, e)
+	return true
+}
+
+func (p *fakePeer) sentCount() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.sent)
+}
+
+type fakePeerState struct{ height int64 }
+
+func (s fakePeerState) GetHeight() int64 { return s.height }
+
+// TestDispatchRoutineDoesNotFloodPeerForUnadvancedHeadTx guards against a
+// dispatcher busy loop: with a single connected peer and a single tx sitting
+// at the head of its lane, the dispatcher has nothing new to send until that
+// tx is committed. It must not keep re-fanning the same tx out to the peer's
+// queue on every round while waiting for a successor that will never come.
+func TestDispatchRoutineDoesNotFloodPeerForUnadvancedHeadTx(t *testing.T) {
+	app := kvstore.NewInMemoryApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	mp, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	mp.config.Broadcast = true
+	mp.config.BroadcastBatchTimeout = 50 * time.Millisecond
+
+	memR := NewReactor(mp.config, mp)
+	memR.SetLogger(log.TestingLogger())
+	require.NoError(t, memR.Start())
+	defer memR.Stop() //nolint:errcheck
+
+	peer := newFakePeer(p2p.ID("peer1"))
+	peer.Set(types.PeerStateKey, fakePeerState{height: 100})
+	memR.InitPeer(peer)
+	memR.AddPeer(peer)
+
+	tx := kvstore.NewTxFromID(1)
+	_, err := mp.CheckTx(tx)
+	require.NoError(t, err)
+	require.NoError(t, mp.FlushAppConn())
+
+	require.Eventually(t, func() bool {
+		return peer.sentCount() >= 1
+	}, time.Second, 10*time.Millisecond, "tx was never sent to the peer")
+
+	// Give the dispatcher plenty of rounds to spin on the still-unadvanced
+	// head-of-lane element before checking it only sent the tx once.
+	time.Sleep(300 * time.Millisecond)
+
+	require.Equal(t, 1, peer.sentCount(),
+		"dispatcher must not resend the head-of-lane tx while its successor isn't ready yet")
+}
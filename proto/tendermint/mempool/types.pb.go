@@ -0,0 +1,557 @@
+// Hand-written to match the wire format generated by protoc-gen-gogo for
+// tendermint/mempool/types.proto, since this tree has no protoc/gogoproto
+// toolchain available to regenerate it. Keep it in sync with types.proto by
+// hand until that toolchain is available.
+
+package mempool
+
+import (
+	fmt "fmt"
+	io "io"
+)
+
+// Txs carries one or more transaction bodies, batched together by the
+// sender when the reactor's broadcast batching bounds allow it.
+type Txs struct {
+	Txs [][]byte `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+}
+
+func (m *Txs) Reset()         { *m = Txs{} }
+func (m *Txs) String() string { return fmt.Sprintf("%+v", *m) }
+func (*Txs) ProtoMessage()    {}
+
+func (m *Txs) GetTxs() [][]byte {
+	if m != nil {
+		return m.Txs
+	}
+	return nil
+}
+
+// TxAnnounce carries the keys (types.TxKey, 32 bytes each) of txs the sender
+// has but has not (yet) pushed the body for, so the receiver can decide
+// whether to request them. Used in "pull" and "hybrid" GossipMode.
+type TxAnnounce struct {
+	Keys [][]byte `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (m *TxAnnounce) Reset()         { *m = TxAnnounce{} }
+func (m *TxAnnounce) String() string { return fmt.Sprintf("%+v", *m) }
+func (*TxAnnounce) ProtoMessage()    {}
+
+func (m *TxAnnounce) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+// TxRequest asks the receiver, who previously sent a TxAnnounce for these
+// keys, to send back the tx bodies (as a Txs message) for whichever of them
+// it still holds.
+type TxRequest struct {
+	Keys [][]byte `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (m *TxRequest) Reset()         { *m = TxRequest{} }
+func (m *TxRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*TxRequest) ProtoMessage()    {}
+
+func (m *TxRequest) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+// Message is the mempool reactor's wire envelope: exactly one of Txs,
+// TxAnnounce or TxRequest is set.
+type Message struct {
+	// Types that are valid to be assigned to Sum:
+	//
+	//	*Message_Txs
+	//	*Message_TxAnnounce
+	//	*Message_TxRequest
+	Sum isMessage_Sum `protobuf_oneof:"sum"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return fmt.Sprintf("%+v", *m) }
+func (*Message) ProtoMessage()    {}
+
+type isMessage_Sum interface {
+	isMessage_Sum()
+	Size() int
+	MarshalTo([]byte) (int, error)
+}
+
+type Message_Txs struct {
+	Txs *Txs `protobuf:"bytes,1,opt,name=txs,proto3,oneof" json:"txs,omitempty"`
+}
+
+type Message_TxAnnounce struct {
+	TxAnnounce *TxAnnounce `protobuf:"bytes,2,opt,name=tx_announce,json=txAnnounce,proto3,oneof" json:"tx_announce,omitempty"`
+}
+
+type Message_TxRequest struct {
+	TxRequest *TxRequest `protobuf:"bytes,3,opt,name=tx_request,json=txRequest,proto3,oneof" json:"tx_request,omitempty"`
+}
+
+func (*Message_Txs) isMessage_Sum()        {}
+func (*Message_TxAnnounce) isMessage_Sum() {}
+func (*Message_TxRequest) isMessage_Sum()  {}
+
+func (m *Message) GetSum() isMessage_Sum {
+	if m != nil {
+		return m.Sum
+	}
+	return nil
+}
+
+func (m *Message) GetTxs() *Txs {
+	if x, ok := m.GetSum().(*Message_Txs); ok {
+		return x.Txs
+	}
+	return nil
+}
+
+func (m *Message) GetTxAnnounce() *TxAnnounce {
+	if x, ok := m.GetSum().(*Message_TxAnnounce); ok {
+		return x.TxAnnounce
+	}
+	return nil
+}
+
+func (m *Message) GetTxRequest() *TxRequest {
+	if x, ok := m.GetSum().(*Message_TxRequest); ok {
+		return x.TxRequest
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------
+// Marshal / Size
+// -----------------------------------------------------------------------
+
+func (m *Txs) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	for _, b := range m.Txs {
+		l := len(b)
+		n += 1 + sovTypes(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *Txs) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	for _, b := range m.Txs {
+		dAtA[i] = 0xa // field 1, wire type 2
+		i++
+		i = encodeVarintTypes(dAtA, i, uint64(len(b)))
+		i += copy(dAtA[i:], b)
+	}
+	return i, nil
+}
+
+func (m *Txs) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TxAnnounce) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	for _, b := range m.Keys {
+		l := len(b)
+		n += 1 + sovTypes(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *TxAnnounce) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	for _, b := range m.Keys {
+		dAtA[i] = 0xa // field 1, wire type 2
+		i++
+		i = encodeVarintTypes(dAtA, i, uint64(len(b)))
+		i += copy(dAtA[i:], b)
+	}
+	return i, nil
+}
+
+func (m *TxAnnounce) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TxRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	for _, b := range m.Keys {
+		l := len(b)
+		n += 1 + sovTypes(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *TxRequest) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	for _, b := range m.Keys {
+		dAtA[i] = 0xa // field 1, wire type 2
+		i++
+		i = encodeVarintTypes(dAtA, i, uint64(len(b)))
+		i += copy(dAtA[i:], b)
+	}
+	return i, nil
+}
+
+func (m *TxRequest) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_Txs) Size() (n int) {
+	if m == nil || m.Txs == nil {
+		return 0
+	}
+	l := m.Txs.Size()
+	return 1 + sovTypes(uint64(l)) + l
+}
+
+func (m *Message_Txs) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	dAtA[i] = 0xa // field 1, wire type 2
+	i++
+	i = encodeVarintTypes(dAtA, i, uint64(m.Txs.Size()))
+	n, err := m.Txs.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	return i + n, nil
+}
+
+func (m *Message_TxAnnounce) Size() (n int) {
+	if m == nil || m.TxAnnounce == nil {
+		return 0
+	}
+	l := m.TxAnnounce.Size()
+	return 1 + sovTypes(uint64(l)) + l
+}
+
+func (m *Message_TxAnnounce) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	dAtA[i] = 0x12 // field 2, wire type 2
+	i++
+	i = encodeVarintTypes(dAtA, i, uint64(m.TxAnnounce.Size()))
+	n, err := m.TxAnnounce.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	return i + n, nil
+}
+
+func (m *Message_TxRequest) Size() (n int) {
+	if m == nil || m.TxRequest == nil {
+		return 0
+	}
+	l := m.TxRequest.Size()
+	return 1 + sovTypes(uint64(l)) + l
+}
+
+func (m *Message_TxRequest) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	dAtA[i] = 0x1a // field 3, wire type 2
+	i++
+	i = encodeVarintTypes(dAtA, i, uint64(m.TxRequest.Size()))
+	n, err := m.TxRequest.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	return i + n, nil
+}
+
+func (m *Message) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	if m.Sum != nil {
+		n += m.Sum.Size()
+	}
+	return n
+}
+
+func (m *Message) MarshalTo(dAtA []byte) (int, error) {
+	if m.Sum == nil {
+		return 0, nil
+	}
+	return m.Sum.MarshalTo(dAtA)
+}
+
+func (m *Message) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+// -----------------------------------------------------------------------
+// Unmarshal
+// -----------------------------------------------------------------------
+
+func (m *Txs) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		fieldNum, wireType, n, err := readTag(dAtA, iNdEx, l)
+		if err != nil {
+			return err
+		}
+		iNdEx = n
+		switch {
+		case fieldNum == 1 && wireType == 2:
+			b, n, err := readBytes(dAtA, iNdEx, l)
+			if err != nil {
+				return err
+			}
+			iNdEx = n
+			m.Txs = append(m.Txs, b)
+		default:
+			n, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			iNdEx += n
+		}
+	}
+	return nil
+}
+
+func (m *TxAnnounce) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		fieldNum, wireType, n, err := readTag(dAtA, iNdEx, l)
+		if err != nil {
+			return err
+		}
+		iNdEx = n
+		switch {
+		case fieldNum == 1 && wireType == 2:
+			b, n, err := readBytes(dAtA, iNdEx, l)
+			if err != nil {
+				return err
+			}
+			iNdEx = n
+			m.Keys = append(m.Keys, b)
+		default:
+			n, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			iNdEx += n
+		}
+	}
+	return nil
+}
+
+func (m *TxRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		fieldNum, wireType, n, err := readTag(dAtA, iNdEx, l)
+		if err != nil {
+			return err
+		}
+		iNdEx = n
+		switch {
+		case fieldNum == 1 && wireType == 2:
+			b, n, err := readBytes(dAtA, iNdEx, l)
+			if err != nil {
+				return err
+			}
+			iNdEx = n
+			m.Keys = append(m.Keys, b)
+		default:
+			n, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			iNdEx += n
+		}
+	}
+	return nil
+}
+
+func (m *Message) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		fieldNum, wireType, n, err := readTag(dAtA, iNdEx, l)
+		if err != nil {
+			return err
+		}
+		iNdEx = n
+		if wireType != 2 {
+			n, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			iNdEx += n
+			continue
+		}
+		b, n, err := readBytes(dAtA, iNdEx, l)
+		if err != nil {
+			return err
+		}
+		iNdEx = n
+		switch fieldNum {
+		case 1:
+			v := &Txs{}
+			if err := v.Unmarshal(b); err != nil {
+				return err
+			}
+			m.Sum = &Message_Txs{Txs: v}
+		case 2:
+			v := &TxAnnounce{}
+			if err := v.Unmarshal(b); err != nil {
+				return err
+			}
+			m.Sum = &Message_TxAnnounce{TxAnnounce: v}
+		case 3:
+			v := &TxRequest{}
+			if err := v.Unmarshal(b); err != nil {
+				return err
+			}
+			m.Sum = &Message_TxRequest{TxRequest: v}
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------
+// Wire-format helpers shared by every message in this file.
+// -----------------------------------------------------------------------
+
+func sovTypes(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			return n
+		}
+	}
+}
+
+func encodeVarintTypes(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+
+// readTag reads the varint tag at dAtA[i:l] and splits it into field number
+// and wire type, returning the index just past the tag.
+func readTag(dAtA []byte, i, l int) (fieldNum int, wireType int, next int, err error) {
+	var tag uint64
+	shift := uint(0)
+	for {
+		if i >= l {
+			return 0, 0, i, io.ErrUnexpectedEOF
+		}
+		b := dAtA[i]
+		i++
+		tag |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+		shift += 7
+	}
+	return int(tag >> 3), int(tag & 0x7), i, nil
+}
+
+// readBytes reads a length-delimited field starting at dAtA[i:l] (i.e. right
+// after its tag) and returns the field bytes plus the index just past them.
+func readBytes(dAtA []byte, i, l int) (b []byte, next int, err error) {
+	var length uint64
+	shift := uint(0)
+	for {
+		if i >= l {
+			return nil, i, io.ErrUnexpectedEOF
+		}
+		c := dAtA[i]
+		i++
+		length |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			break
+		}
+		shift += 7
+	}
+	end := i + int(length)
+	if end < i || end > l {
+		return nil, i, io.ErrUnexpectedEOF
+	}
+	return dAtA[i:end], end, nil
+}
+
+// skipTypes skips a single field's value, given dAtA positioned right after
+// its tag, and reports how many bytes it consumed.
+func skipTypes(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	fieldNum, wireType, i, err := readTag(dAtA, 0, l)
+	_ = fieldNum
+	if err != nil {
+		return 0, err
+	}
+	switch wireType {
+	case 0: // varint
+		for {
+			if i >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[i]
+			i++
+			if b < 0x80 {
+				break
+			}
+		}
+		return i, nil
+	case 1: // 64-bit
+		if i+8 > l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return i + 8, nil
+	case 2: // length-delimited
+		_, next, err := readBytes(dAtA, i, l)
+		if err != nil {
+			return 0, err
+		}
+		return next, nil
+	case 5: // 32-bit
+		if i+4 > l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return i + 4, nil
+	default:
+		return 0, fmt.Errorf("proto: unsupported wire type %d", wireType)
+	}
+}